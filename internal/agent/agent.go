@@ -1,7 +1,9 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"Orkflow/internal/logging"
@@ -10,16 +12,28 @@ import (
 	"Orkflow/pkg/types"
 )
 
-const maxRetries = 3
-
 type Runner struct {
 	Config          *types.WorkflowConfig
 	Context         *ContextManager
 	Clients         map[string]LLMClient
-	SessionHistory  string
-	MessageCallback func(agentID, role, content string) // Called when agent completes
-	SharedMemory    *memory.SharedMemory                // Shared memory for inter-agent communication
-	Logger          *logging.Logger                     // Execution logger
+	SessionHistory  []types.ChatMessage
+	MessageCallback func(msg memory.Message)    // Called when agent completes
+	TokenCallback   func(agentID, token string) // Receives streamed text; an empty token ends the agent's stream
+	SharedMemory    *memory.SharedMemory        // Shared memory for inter-agent communication
+	Logger          *logging.Logger             // Execution logger
+	Stats           StatsRecorder               // Receives timing and token usage when set
+	CallGuard       func(agentID string) error  // Checked before every model call; an error stops the agent without retries
+	Tools           *tools.Registry             // Tools of this workflow run: the built-ins plus its MCP tools
+
+	filePolicy *tools.FilePolicy // Confines the file and shell tools, from the workflow's file_access
+}
+
+// StatsRecorder receives timing and token usage as agents run. Every model
+// call is recorded, including tool follow-ups and collaborative turns.
+type StatsRecorder interface {
+	StartAgent(agentID, role, provider, model string)
+	RecordCall(agentID, provider, model string, usage types.Usage, duration time.Duration)
+	CompleteAgent(agentID string, duration time.Duration)
 }
 
 func NewRunner(config *types.WorkflowConfig) *Runner {
@@ -27,6 +41,7 @@ func NewRunner(config *types.WorkflowConfig) *Runner {
 		Config:  config,
 		Context: NewContextManager(),
 		Clients: make(map[string]LLMClient),
+		Tools:   tools.NewWorkflowRegistry(),
 	}
 
 	for name, model := range config.Models {
@@ -39,11 +54,24 @@ func NewRunner(config *types.WorkflowConfig) *Runner {
 		)
 	}
 
+	if config.FileAccess != nil {
+		runner.filePolicy = tools.NewFilePolicy(*config.FileAccess)
+	}
+	if config.Shell != nil {
+		runner.Tools.Register(tools.NewShellTool(*config.Shell, runner.filePolicy))
+	}
+	if config.HTTP != nil {
+		runner.Tools.Register(tools.NewHTTPTool(*config.HTTP))
+	}
+	if config.Script != nil {
+		runner.Tools.Register(tools.NewScriptTool(*config.Script))
+	}
+
 	return runner
 }
 
 // SetSessionHistory stores previous session context
-func (r *Runner) SetSessionHistory(history string) {
+func (r *Runner) SetSessionHistory(history []types.ChatMessage) {
 	r.SessionHistory = history
 }
 
@@ -64,10 +92,32 @@ func getSpinnerForAgent(agentID string) []string {
 	return spinnerStyles[hash%len(spinnerStyles)]
 }
 
-func (r *Runner) RunAgent(agentDef *types.Agent) (string, error) {
+func (r *Runner) RunAgent(ctx context.Context, agentDef *types.Agent) (string, error) {
+	return r.RunAgentIteration(ctx, agentDef, 0)
+}
+
+// RunAgentIteration runs an agent as part of a loop iteration. The iteration
+// index (1-based, 0 outside loops) is recorded with the agent's output.
+// The agent's timeout, if set, bounds the whole run including retries.
+func (r *Runner) RunAgentIteration(ctx context.Context, agentDef *types.Agent, iteration int) (string, error) {
+	response, _, err := r.runAgent(ctx, agentDef, iteration, true)
+	return response, err
+}
+
+// runAgent runs an agent once and returns its response and the model that
+// answered. Unrecorded runs, such as a supervisor's delegating turns, leave
+// the context history, stats, shared memory, output log and session alone;
+// the caller records the answer it settles on with recordOutput.
+func (r *Runner) runAgent(ctx context.Context, agentDef *types.Agent, iteration int, record bool) (string, string, error) {
 	client, ok := r.Clients[agentDef.Model]
 	if !ok {
-		return "", fmt.Errorf("model not found: %s", agentDef.Model)
+		return "", "", fmt.Errorf("model not found: %s", agentDef.Model)
+	}
+
+	if agentDef.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, agentDef.Timeout)
+		defer cancel()
 	}
 
 	// Wait for required keys from shared memory
@@ -79,10 +129,10 @@ func (r *Runner) RunAgent(agentDef *types.Agent) (string, error) {
 		for _, key := range agentDef.Requires {
 			val, err := r.SharedMemory.WaitFor(key, 5*time.Minute) // 5 min timeout for slow models
 			if err != nil {
-				return "", fmt.Errorf("agent %s: failed to get required key '%s': %w", agentDef.ID, key, err)
+				return "", "", fmt.Errorf("agent %s: failed to get required key '%s': %w", agentDef.ID, key, err)
 			}
 			// Inject into context
-			r.Context.AddOutput(fmt.Sprintf("shared:%s", key), fmt.Sprintf("%v", val))
+			r.Context.AddOutput(fmt.Sprintf("shared:%s", key), memoryText(val))
 			fmt.Printf("[%s] ✓ Received '%s' from shared memory\n", agentDef.ID, key)
 			if r.Logger != nil {
 				r.Logger.LogAgent(agentDef.ID, "SHARED_MEMORY_RECEIVED", key)
@@ -90,99 +140,85 @@ func (r *Runner) RunAgent(agentDef *types.Agent) (string, error) {
 		}
 	}
 
-	prompt := r.buildPrompt(agentDef)
-	spinner := getSpinnerForAgent(agentDef.ID)
+	// Fill in {{ }} templates from shared memory and earlier outputs
+	rendered, err := r.renderPrompt(agentDef)
+	if err != nil {
+		return "", "", fmt.Errorf("agent %s: %w", agentDef.ID, err)
+	}
+	agentDef = rendered
+
+	// Prefer the provider's native tool calling; otherwise tools are described
+	// in the prompt and called with ```tool: fences
+	conv := r.newConversation(client, agentDef)
+	messages := r.buildMessages(agentDef, conv.describeTools())
 	fmt.Printf("[%s] Running agent: %s\n", agentDef.ID, agentDef.Role)
 	if r.Logger != nil {
 		r.Logger.LogAgent(agentDef.ID, "STARTED", fmt.Sprintf("Role: %s", agentDef.Role))
 	}
+	if record {
+		r.startStats(agentDef)
+	}
 
 	var response string
-	var err error
 	startTime := time.Now()
 
-	// Start progress indicator (log-based for parallel compatibility)
+	// Start progress indicator (log-based for parallel compatibility).
+	// Streamed output shows progress by itself.
 	done := make(chan bool)
-	go func() {
-		i := 0
-		lastLog := time.Now()
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				elapsed := time.Since(startTime).Seconds()
-				// Log every 5 seconds for parallel agents
-				if time.Since(lastLog) >= 5*time.Second {
-					fmt.Printf("[%s] %s Still generating... (%.0fs)\n", agentDef.ID, spinner[i%len(spinner)], elapsed)
-					lastLog = time.Now()
-				}
-				i++
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-	}()
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		response, err = client.Generate(prompt)
-		if err == nil {
-			break
-		}
-		fmt.Printf("[%s] Attempt %d failed: %v\n", agentDef.ID, attempt, err)
-
-		if attempt < maxRetries {
-			fmt.Printf("[%s] Retrying in %d seconds...\n", agentDef.ID, attempt)
-			time.Sleep(time.Second * time.Duration(attempt))
-		}
+	if !conv.streams() {
+		go r.showProgress(agentDef.ID, startTime, done)
 	}
 
+	response, err = conv.generate(ctx, messages)
+	r.endStream(agentDef.ID)
+
 	close(done)
 	elapsed := time.Since(startTime)
 
+	if err == nil {
+		err = ctx.Err()
+	}
 	if err != nil {
-		return "", fmt.Errorf("agent %s failed after %d attempts: %w", agentDef.ID, maxRetries, err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if r.Logger != nil {
+				r.Logger.LogAgent(agentDef.ID, "CANCELLED", ctxErr.Error())
+			}
+			return "", "", fmt.Errorf("agent %s: %w", agentDef.ID, agentContextError(agentDef, ctxErr))
+		}
+		return "", "", fmt.Errorf("agent %s: %w", agentDef.ID, err)
 	}
 
 	fmt.Printf("[%s] ✓ Completed in %.1fs (%d chars)\n", agentDef.ID, elapsed.Seconds(), len(response))
 
-	// Handle tool calls if agent has tools
-	if len(agentDef.Tools) > 0 && tools.HasToolCalls(response) {
-		toolCalls := tools.ParseToolCalls(response)
-		if len(toolCalls) > 0 {
-			results := tools.ExecuteToolCalls(toolCalls)
-
-			// Log tool execution
+	// Keep running tools until the model answers without calling one, then
+	// hold the answer to the agent's schema
+	response, err = r.runToolLoop(ctx, agentDef, conv, response)
+	var structured interface{}
+	if err == nil && agentDef.OutputSchema != nil {
+		structured, response, err = r.structureOutput(ctx, agentDef, conv, response)
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
 			if r.Logger != nil {
-				for i, res := range results {
-					input := toolCalls[i].Input
-					output := res.Output
-					if res.Error != nil {
-						output = fmt.Sprintf("ERROR: %v", res.Error)
-					}
-					r.Logger.LogToolCall(res.ToolName, input, output)
-				}
-			}
-
-			toolOutput := tools.FormatToolResults(results)
-
-			// Make a follow-up call with tool results
-			if toolOutput != "" {
-				followupPrompt := prompt + "\n\nPrevious response:\n" + response + toolOutput + "\n\nNow provide your final response incorporating the tool results:"
-				followupResponse, followupErr := client.Generate(followupPrompt)
-				if followupErr == nil {
-					response = followupResponse
-					fmt.Printf("[%s] ✓ Follow-up completed (%d chars)\n", agentDef.ID, len(response))
-				}
+				r.Logger.LogAgent(agentDef.ID, "CANCELLED", ctxErr.Error())
 			}
+			return "", "", fmt.Errorf("agent %s: %w", agentDef.ID, agentContextError(agentDef, ctxErr))
 		}
+		return "", "", fmt.Errorf("agent %s: %w", agentDef.ID, err)
 	}
 
-	r.Context.AddOutput(agentDef.ID, response)
+	if !record {
+		return response, conv.model, nil
+	}
 
-	// Publish outputs to shared memory
+	// Publish outputs to shared memory, parsed when the agent has a schema
 	if r.SharedMemory != nil && len(agentDef.Outputs) > 0 {
+		var published interface{} = response
+		if structured != nil {
+			published = structured
+		}
 		for _, key := range agentDef.Outputs {
-			r.SharedMemory.Set(key, response)
+			r.SharedMemory.Set(key, published)
 			fmt.Printf("[%s] 📤 Published '%s' to shared memory\n", agentDef.ID, key)
 			if r.Logger != nil {
 				r.Logger.LogAgent(agentDef.ID, "SHARED_MEMORY_PUBLISH", key)
@@ -190,59 +226,235 @@ func (r *Runner) RunAgent(agentDef *types.Agent) (string, error) {
 		}
 	}
 
+	r.recordOutput(agentDef, response, conv.model, iteration, time.Since(startTime))
+	return response, conv.model, nil
+}
+
+// recordOutput adds an agent's answer to the context history and stats,
+// logs it and saves it to the session
+func (r *Runner) recordOutput(agentDef *types.Agent, response, model string, iteration int, duration time.Duration) {
+	r.Context.AddIterationOutput(agentDef.ID, response, iteration)
+	if r.Stats != nil {
+		r.Stats.CompleteAgent(agentDef.ID, duration)
+	}
+
 	// Log full output
 	if r.Logger != nil {
 		r.Logger.LogAgentOutput(agentDef.ID, agentDef.Role, response)
-		r.Logger.LogAgent(agentDef.ID, "COMPLETED", fmt.Sprintf("Duration: %.1fs, Chars: %d", elapsed.Seconds(), len(response)))
+		r.Logger.LogAgent(agentDef.ID, "COMPLETED", fmt.Sprintf("Duration: %.1fs, Chars: %d", duration.Seconds(), len(response)))
 	}
 
 	// Save to session if callback is set
 	if r.MessageCallback != nil {
-		r.MessageCallback(agentDef.ID, agentDef.Role, response)
+		r.MessageCallback(memory.Message{
+			AgentID:   agentDef.ID,
+			Role:      agentDef.Role,
+			Content:   response,
+			Iteration: iteration,
+			Model:     r.modelLabel(model),
+		})
 	}
-
-	return response, nil
 }
 
-func (r *Runner) buildPrompt(agentDef *types.Agent) string {
-	prompt := agentDef.GetPrompt()
+// showProgress logs a spinner line every few seconds until done is closed
+func (r *Runner) showProgress(agentID string, startTime time.Time, done <-chan bool) {
+	spinner := getSpinnerForAgent(agentID)
+	i := 0
+	lastLog := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			elapsed := time.Since(startTime).Seconds()
+			// Log every 5 seconds for parallel agents
+			if time.Since(lastLog) >= 5*time.Second {
+				fmt.Printf("[%s] %s Still generating... (%.0fs)\n", agentID, spinner[i%len(spinner)], elapsed)
+				lastLog = time.Now()
+			}
+			i++
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
 
-	// Add session history from previous runs
-	if r.SessionHistory != "" {
-		prompt = r.SessionHistory + "\n\n" + prompt
+// tokenHandler returns the stream callback for an agent, or nil when
+// streaming is off
+func (r *Runner) tokenHandler(agentID string) func(token string) {
+	if r.TokenCallback == nil {
+		return nil
+	}
+	return func(token string) {
+		if token != "" {
+			r.TokenCallback(agentID, token)
+		}
 	}
+}
 
-	// Add context from current run
-	context := r.Context.GetContext()
-	if context != "" {
-		prompt = prompt + "\n\n" + context
+// newConversation starts an agent's conversation with its model, wired to
+// the runner's streaming, stats, budget, retry and fallback handling
+func (r *Runner) newConversation(client LLMClient, agentDef *types.Agent) *toolConversation {
+	conv := newToolConversation(withParams(client, r.paramsFor(agentDef.Model, agentDef)), r.toolsFor(agentDef))
+	conv.model = agentDef.Model
+	conv.onToken = r.tokenHandler(agentDef.ID)
+	conv.onCall = r.callHandler(agentDef.ID)
+	conv.guard = r.guardHandler(agentDef.ID)
+	conv.retry = r.retryPolicyFor(agentDef.Model, agentDef)
+	conv.onRetry = r.retryHandler(agentDef.ID)
+	conv.fallback = r.fallbackHandler(agentDef, conv)
+	return conv
+}
+
+// callHandler returns the callback recording an agent's model calls, or nil
+// when stats are off
+func (r *Runner) callHandler(agentID string) func(model string, usage types.Usage, duration time.Duration) {
+	if r.Stats == nil {
+		return nil
+	}
+	return func(model string, usage types.Usage, duration time.Duration) {
+		provider, name := r.modelInfo(model)
+		r.Stats.RecordCall(agentID, provider, name, usage, duration)
 	}
+}
 
-	// Add tool descriptions if agent has tools or toolsets
-	var allTools []tools.Tool
+// guardHandler returns the check run before each of an agent's model
+// calls, or nil when there is none
+func (r *Runner) guardHandler(agentID string) func() error {
+	if r.CallGuard == nil {
+		return nil
+	}
+	return func() error {
+		return r.CallGuard(agentID)
+	}
+}
 
-	// 1. Add explicitly listed tools
-	if len(agentDef.Tools) > 0 {
-		agentTools, err := tools.GetByNames(agentDef.Tools)
-		if err == nil {
-			allTools = append(allTools, agentTools...)
+// retryHandler reports an agent's failed model calls before they are retried
+func (r *Runner) retryHandler(agentID string) func(attempt int, err error, wait time.Duration) {
+	return func(attempt int, err error, wait time.Duration) {
+		r.endStream(agentID)
+		fmt.Printf("[%s] Attempt %d failed: %v\n", agentID, attempt, err)
+		fmt.Printf("[%s] Retrying in %.1fs...\n", agentID, wait.Seconds())
+		if r.Logger != nil {
+			r.Logger.LogAgent(agentID, "RETRY", fmt.Sprintf("Attempt %d: %v", attempt, err))
 		}
 	}
+}
+
+// startStats records the start of an agent run under the provider and its
+// model name, which pricing is keyed by
+func (r *Runner) startStats(agentDef *types.Agent) {
+	if r.Stats == nil {
+		return
+	}
+	provider, model := r.modelInfo(agentDef.Model)
+	r.Stats.StartAgent(agentDef.ID, agentDef.Role, provider, model)
+}
 
-	// 2. Add tools from toolsets (MCP servers)
-	if len(agentDef.Toolsets) > 0 {
-		for _, toolset := range agentDef.Toolsets {
-			// Get tools starting with "serverName."
-			setTools := tools.GetByPrefix(toolset + ".")
-			allTools = append(allTools, setTools...)
+// paramsFor returns the generation parameters of an agent's calls to a
+// model: the model's parameters overridden by the agent's own
+func (r *Runner) paramsFor(model string, agentDef *types.Agent) types.GenerationParams {
+	params := r.Config.Models[model].GenerationParams.Merge(agentDef.GenerationParams)
+	if agentDef.OutputSchema != nil {
+		params.Schema = agentDef.OutputSchema.Schema
+	}
+	return params
+}
+
+// modelInfo returns the provider and model name of a configured model,
+// falling back to the configured name when it has no entry
+func (r *Runner) modelInfo(name string) (provider, model string) {
+	model = name
+	if m, ok := r.Config.Models[name]; ok {
+		provider = m.Provider
+		if m.Model != "" {
+			model = m.Model
 		}
 	}
+	return provider, model
+}
+
+// modelLabel names a configured model as provider/model
+func (r *Runner) modelLabel(name string) string {
+	provider, model := r.modelInfo(name)
+	if provider == "" {
+		return model
+	}
+	return provider + "/" + model
+}
+
+// endStream tells the stream consumer that an agent's streamed text is
+// finished, so log lines that follow start on their own line
+func (r *Runner) endStream(agentID string) {
+	if r.TokenCallback != nil {
+		r.TokenCallback(agentID, "")
+	}
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// agentContextError describes why an agent's context ended, naming the
+// agent timeout when that is what expired.
+func agentContextError(agentDef *types.Agent, err error) error {
+	if err == context.DeadlineExceeded && agentDef.Timeout > 0 {
+		return fmt.Errorf("timed out after %v: %w", agentDef.Timeout, err)
+	}
+	return err
+}
 
-	if len(allTools) > 0 {
-		prompt = prompt + "\n\n" + tools.FormatToolsForPrompt(allTools)
+// buildMessages assembles an agent's conversation: its persona and any
+// fence-format tool descriptions as the system message, the previous
+// session, its instruction, then the outputs of the current run
+func (r *Runner) buildMessages(agentDef *types.Agent, toolDocs string) []types.ChatMessage {
+	var messages []types.ChatMessage
+	if system := systemPrompt(agentDef, toolDocs); system != "" {
+		messages = append(messages, types.ChatMessage{Role: types.RoleSystem, Content: system})
 	}
 
-	return prompt
+	// Add session history from previous runs
+	messages = append(messages, r.SessionHistory...)
+
+	instruction := types.ChatMessage{Role: types.RoleUser, Content: agentDef.GetPrompt()}
+	messages = append(messages, instruction)
+
+	// Add context from current run
+	messages = append(messages, r.Context.Messages(agentDef.ID)...)
+
+	// The model answers a user turn, so repeat the instruction when the
+	// agent's own output came last
+	if messages[len(messages)-1].Role == types.RoleAssistant {
+		messages = append(messages, instruction)
+	}
+
+	return mergeTurns(messages)
+}
+
+// systemPrompt describes the agent's persona and the shape of its reply,
+// followed by tool docs
+func systemPrompt(agentDef *types.Agent, toolDocs string) string {
+	var parts []string
+	if agentDef.Role != "" {
+		parts = append(parts, fmt.Sprintf("You are %s.", agentDef.Role))
+	}
+	if agentDef.Description != "" {
+		parts = append(parts, agentDef.Description)
+	}
+	if agentDef.OutputSchema != nil {
+		parts = append(parts, schemaInstructions(agentDef.OutputSchema.Schema))
+	}
+	if toolDocs != "" {
+		parts = append(parts, toolDocs)
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 func (r *Runner) GetAgent(id string) *types.Agent {
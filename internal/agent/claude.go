@@ -2,30 +2,200 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
+
+	"Orkflow/pkg/types"
 )
 
+const claudeEndpoint = "https://api.anthropic.com/v1/messages"
+
+// claudeMaxTokens is sent when no max_tokens is set, since the Messages
+// API requires one
+const claudeMaxTokens = 4096
+
 type ClaudeClient struct {
-	APIKey string
-	Model  string
+	APIKey   string
+	Model    string
+	Endpoint string                 // Messages API URL, defaults to the Anthropic API
+	Params   types.GenerationParams // Sent with every request
+}
+
+// WithParams returns a copy of the client sending the given parameters
+func (c *ClaudeClient) WithParams(params types.GenerationParams) LLMClient {
+	client := *c
+	client.Params = params
+	return &client
+}
+
+func (c *ClaudeClient) Generate(ctx context.Context, prompt string) (string, error) {
+	reply, err := c.Chat(ctx, []types.ChatMessage{{Role: types.RoleUser, Content: prompt}})
+	return reply.Content, err
+}
+
+// Chat sends a conversation and returns the assistant's text reply
+func (c *ClaudeClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	return c.GenerateWithTools(ctx, messages, nil)
+}
+
+// GenerateWithTools sends a conversation with tool definitions and returns
+// the assistant's reply, which may contain tool_use blocks
+func (c *ClaudeClient) GenerateWithTools(ctx context.Context, messages []types.ChatMessage, tools []ToolSpec) (types.ChatMessage, error) {
+	payload := c.payload(messages)
+	if len(tools) > 0 {
+		defs := make([]map[string]interface{}, 0, len(tools))
+		for _, tool := range tools {
+			defs = append(defs, map[string]interface{}{
+				"name":         tool.Name,
+				"description":  tool.Description,
+				"input_schema": tool.Parameters,
+			})
+		}
+		payload["tools"] = defs
+	}
+
+	var result struct {
+		Content []claudeBlock `json:"content"`
+		Usage   *claudeUsage  `json:"usage"`
+	}
+	if err := c.post(ctx, payload, &result); err != nil {
+		return types.ChatMessage{}, err
+	}
+
+	if len(result.Content) == 0 {
+		return types.ChatMessage{}, fmt.Errorf("no response from claude")
+	}
+
+	reply := types.ChatMessage{Role: "assistant", Usage: result.Usage.toUsage()}
+	var text []string
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			args, _ := block.Input.(map[string]interface{})
+			reply.ToolCalls = append(reply.ToolCalls, types.ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: args,
+			})
+		}
+	}
+	reply.Content = strings.Join(text, "\n")
+	return reply, nil
+}
+
+// GenerateStream streams a message over server-sent events. Input tokens
+// are reported by message_start and output tokens by message_delta.
+func (c *ClaudeClient) GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error) {
+	payload := c.payload(messages)
+	payload["stream"] = true
+
+	resp, err := c.send(ctx, payload)
+	if err != nil {
+		return types.ChatMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	reply := types.ChatMessage{Role: types.RoleAssistant}
+	var usage claudeUsage
+	var sb strings.Builder
+	err = readSSE(resp.Body, func(data string) error {
+		var event struct {
+			Type    string `json:"type"`
+			Message struct {
+				Usage *claudeUsage `json:"usage"`
+			} `json:"message"`
+			Delta struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"delta"`
+			Usage *claudeUsage `json:"usage"`
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("claude: invalid stream event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message.Usage != nil {
+				usage = *event.Message.Usage
+				reply.Usage = usage.toUsage()
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				sb.WriteString(event.Delta.Text)
+				onToken(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+				reply.Usage = usage.toUsage()
+			}
+		case "error":
+			return fmt.Errorf("claude api error: %s", event.Error.Message)
+		}
+		return nil
+	})
+	reply.Content = sb.String()
+	return reply, err
 }
 
-func (c *ClaudeClient) Generate(prompt string) (string, error) {
+// payload builds a messages request body for a conversation. The API has
+// no seed or JSON mode, so those parameters are not sent.
+func (c *ClaudeClient) payload(messages []types.ChatMessage) map[string]interface{} {
+	system, converted := toClaudeMessages(messages)
+	maxTokens := c.Params.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = claudeMaxTokens
+	}
 	payload := map[string]interface{}{
 		"model":      c.Model,
-		"max_tokens": 4096,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"max_tokens": maxTokens,
+		"messages":   converted,
+	}
+	if system != "" {
+		payload["system"] = system
+	}
+	if c.Params.Temperature != nil {
+		payload["temperature"] = *c.Params.Temperature
+	}
+	if c.Params.TopP != nil {
+		payload["top_p"] = *c.Params.TopP
+	}
+	if len(c.Params.Stop) > 0 {
+		payload["stop_sequences"] = c.Params.Stop
+	}
+	return payload
+}
+
+// post sends a messages request and decodes the response into result
+func (c *ClaudeClient) post(ctx context.Context, payload interface{}, result interface{}) error {
+	resp, err := c.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// send posts a messages request, returning an error for non-200 responses
+func (c *ClaudeClient) send(ctx context.Context, payload interface{}) (*http.Response, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = claudeEndpoint
 	}
 
 	body, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("x-api-key", c.APIKey)
@@ -34,27 +204,86 @@ func (c *ClaudeClient) Generate(prompt string) (string, error) {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("claude api error: %s", string(respBody))
+		return nil, newAPIError("claude", resp)
 	}
 
-	var result struct {
-		Content []struct {
-			Text string `json:"text"`
-		} `json:"content"`
+	return resp, nil
+}
+
+// claudeBlock is a content block in the Anthropic wire format
+type claudeBlock struct {
+	Type      string      `json:"type"`
+	Text      string      `json:"text,omitempty"`
+	ID        string      `json:"id,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Input     interface{} `json:"input,omitempty"`
+	ToolUseID string      `json:"tool_use_id,omitempty"`
+	Content   string      `json:"content,omitempty"`
+}
+
+// claudeUsage is the token usage block of a messages response. Anthropic
+// counts cache reads and writes separately from input_tokens.
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+}
+
+// toUsage converts the block, returning nil when the response had none
+func (u *claudeUsage) toUsage() *types.Usage {
+	if u == nil {
+		return nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	return &types.Usage{
+		InputTokens:       u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		CachedInputTokens: u.CacheReadInputTokens,
+		OutputTokens:      u.OutputTokens,
 	}
+}
 
-	if len(result.Content) == 0 {
-		return "", fmt.Errorf("no response from claude")
+type claudeMessage struct {
+	Role    string        `json:"role"`
+	Content []claudeBlock `json:"content"`
+}
+
+// toClaudeMessages converts a conversation to the Anthropic format. System
+// messages move to the top-level system prompt, and tool results become
+// tool_result blocks in a user message, merged when they follow each other.
+func toClaudeMessages(messages []types.ChatMessage) (string, []claudeMessage) {
+	var system []string
+	var result []claudeMessage
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "tool":
+			block := claudeBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if n := len(result); n > 0 && len(result[n-1].Content) > 0 && result[n-1].Content[0].Type == "tool_result" {
+				result[n-1].Content = append(result[n-1].Content, block)
+				continue
+			}
+			result = append(result, claudeMessage{Role: "user", Content: []claudeBlock{block}})
+		default:
+			out := claudeMessage{Role: msg.Role}
+			if msg.Content != "" {
+				out.Content = append(out.Content, claudeBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				args := call.Arguments
+				if args == nil {
+					args = map[string]interface{}{}
+				}
+				out.Content = append(out.Content, claudeBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: args})
+			}
+			result = append(result, out)
+		}
 	}
 
-	return result.Content[0].Text, nil
+	return strings.Join(system, "\n\n"), result
 }
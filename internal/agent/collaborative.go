@@ -1,11 +1,11 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"Orkflow/internal/memory"
-	"Orkflow/internal/tools"
 	"Orkflow/pkg/types"
 )
 
@@ -25,7 +25,7 @@ const (
 //     - Parses and sends outgoing messages
 //     - Checks for DONE signal
 //  3. Returns the final output
-func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.MessageChannel) (string, error) {
+func (r *Runner) RunCollaborativeAgent(ctx context.Context, agentDef *types.Agent, channel *memory.MessageChannel) (string, error) {
 	client, ok := r.Clients[agentDef.Model]
 	if !ok {
 		return "", fmt.Errorf("model not found: %s", agentDef.Model)
@@ -47,12 +47,34 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 	var conversation []string
 	var allReceivedMessages []memory.ChannelMessage
 
+	// Tool calls in a turn run through the same loop as RunAgent
+	conv := r.newConversation(client, agentDef)
+	conv.followup = "Now provide your final response incorporating the tool results (and any messages you want to send):"
+
 	fmt.Printf("[%s] 🤝 Starting collaborative agent (max %d turns)\n", agentDef.ID, maxTurns)
 	if r.Logger != nil {
 		r.Logger.LogAgent(agentDef.ID, "COLLABORATIVE_START", fmt.Sprintf("MaxTurns: %d", maxTurns))
 	}
+	r.startStats(agentDef)
+	started := time.Now()
 
 	for turn := 0; turn < maxTurns; turn++ {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("[%s] turn %d: %w", agentDef.ID, turn+1, err)
+		}
+
+		// Stop when the collaboration has ended or the shared budget is spent
+		if channel.IsClosed() {
+			break
+		}
+		if !channel.TakeTurn() {
+			fmt.Printf("[%s] ⏹️  Shared turn budget exhausted\n", agentDef.ID)
+			if r.Logger != nil {
+				r.Logger.LogAgent(agentDef.ID, "TURN_BUDGET_EXHAUSTED", fmt.Sprintf("Turn: %d", turn+1))
+			}
+			break
+		}
+
 		// 1. Collect new messages (non-blocking with timeout)
 		newMessages := r.collectMessages(inbox, agentDef.ListensTo)
 		allReceivedMessages = append(allReceivedMessages, newMessages...)
@@ -67,11 +89,16 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 
 		// 2. Build prompt with message context
 		prompt := r.buildCollaborativePrompt(agentDef, allReceivedMessages, conversation, turn)
+		messages := []types.ChatMessage{{Role: types.RoleUser, Content: prompt}}
+		if system := systemPrompt(agentDef, conv.describeTools()); system != "" {
+			messages = append([]types.ChatMessage{{Role: types.RoleSystem, Content: system}}, messages...)
+		}
 
 		// 3. Generate response
 		fmt.Printf("[%s] 💭 Turn %d/%d - Generating response...\n", agentDef.ID, turn+1, maxTurns)
 		startTime := time.Now()
-		response, err := client.Generate(prompt)
+		response, err := r.generateTurn(ctx, conv, agentDef, messages)
+		r.endStream(agentDef.ID)
 		elapsed := time.Since(startTime)
 
 		if err != nil {
@@ -86,49 +113,6 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 			r.Logger.LogAgentOutput(agentDef.ID, fmt.Sprintf("Turn %d", turn+1), response)
 		}
 
-		// Handle tool calls (NEW: Added support for tools in collaborative mode)
-		if (len(agentDef.Tools) > 0 || len(agentDef.Toolsets) > 0) && tools.HasToolCalls(response) {
-			toolCalls := tools.ParseToolCalls(response)
-			if len(toolCalls) > 0 {
-				fmt.Printf("[%s] 🛠️ Executing %d tool calls...\n", agentDef.ID, len(toolCalls))
-				results := tools.ExecuteToolCalls(toolCalls)
-
-				// Log tool execution
-				if r.Logger != nil {
-					for i, res := range results {
-						input := toolCalls[i].Input
-						output := res.Output
-						if res.Error != nil {
-							output = fmt.Sprintf("ERROR: %v", res.Error)
-						}
-						r.Logger.LogToolCall(res.ToolName, input, output)
-					}
-				}
-
-				toolOutput := tools.FormatToolResults(results)
-
-				// Make a follow-up call with tool results
-				if toolOutput != "" {
-					fmt.Printf("[%s] 🔄 Processing tool results...\n", agentDef.ID)
-					followupPrompt := prompt + "\n\nPrevious response:\n" + response + toolOutput + "\n\nNow provide your final response incorporating the tool results (and any messages you want to send):"
-					
-					followupResponse, followupErr := client.Generate(followupPrompt)
-					if followupErr == nil {
-						response = followupResponse
-						conversation = append(conversation, response) // Add follow-up to conversation
-						fmt.Printf("[%s] ✓ Follow-up completed (%d chars)\n", agentDef.ID, len(response))
-						
-						// Log follow-up output
-						if r.Logger != nil {
-							r.Logger.LogAgentOutput(agentDef.ID, fmt.Sprintf("Turn %d (Follow-up)", turn+1), response)
-						}
-					} else {
-						fmt.Printf("[%s] ⚠️ Follow-up failed: %v\n", agentDef.ID, followupErr)
-					}
-				}
-			}
-		}
-
 		// 4. Parse and send outgoing messages
 		outgoing := ParseOutgoingMessages(response)
 		for _, msg := range outgoing {
@@ -155,6 +139,7 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 			if r.Logger != nil {
 				r.Logger.LogAgent(agentDef.ID, "COLLABORATIVE_DONE", fmt.Sprintf("Turn: %d", turn+1))
 			}
+			channel.MarkDone(agentDef.ID)
 			break
 		}
 
@@ -164,6 +149,10 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 
 	// Extract and return final output
 	finalOutput := ExtractFinalOutput(conversation)
+	r.Context.AddOutput(agentDef.ID, finalOutput)
+	if r.Stats != nil {
+		r.Stats.CompleteAgent(agentDef.ID, time.Since(started))
+	}
 
 	// Publish to shared memory if outputs defined
 	if r.SharedMemory != nil && len(agentDef.Outputs) > 0 {
@@ -176,9 +165,37 @@ func (r *Runner) RunCollaborativeAgent(agentDef *types.Agent, channel *memory.Me
 		}
 	}
 
+	// Save to session if callback is set
+	if r.MessageCallback != nil {
+		r.MessageCallback(memory.Message{
+			AgentID: agentDef.ID,
+			Role:    agentDef.Role,
+			Content: finalOutput,
+			Model:   r.modelLabel(conv.model),
+		})
+	}
+
 	return finalOutput, nil
 }
 
+// generateTurn makes one collaborative LLM call and runs any tools it asks
+// for, bounded by the agent's timeout when one is set
+func (r *Runner) generateTurn(ctx context.Context, conv *toolConversation, agentDef *types.Agent, messages []types.ChatMessage) (string, error) {
+	if agentDef.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, agentDef.Timeout)
+		defer cancel()
+	}
+	response, err := conv.generate(ctx, messages)
+	if err == nil {
+		response, err = r.runToolLoop(ctx, agentDef, conv, response)
+	}
+	if err != nil && ctx.Err() != nil {
+		return "", agentContextError(agentDef, ctx.Err())
+	}
+	return response, err
+}
+
 // collectMessages gathers messages from the inbox channel with a timeout.
 // It filters messages to only include those from agents in listenTo list (if specified).
 func (r *Runner) collectMessages(inbox <-chan memory.ChannelMessage, listenTo []string) []memory.ChannelMessage {
@@ -264,7 +281,7 @@ This is turn %d. Communicate with other agents as needed, then provide your anal
 	if r.SharedMemory != nil && len(agentDef.Requires) > 0 {
 		for _, key := range agentDef.Requires {
 			if val, ok := r.SharedMemory.Get(key); ok {
-				prompt += fmt.Sprintf("\n## Context - %s:\n%v\n", key, val)
+				prompt += fmt.Sprintf("\n## Context - %s:\n%s\n", key, memoryText(val))
 			}
 		}
 	}
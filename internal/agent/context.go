@@ -2,17 +2,23 @@ package agent
 
 import (
 	"fmt"
-	"strings"
+	"sync"
 	"time"
+
+	"Orkflow/pkg/types"
 )
 
 type AgentOutput struct {
 	AgentID   string
 	Response  string
+	Iteration int // Loop iteration, 0 outside loops
 	Timestamp time.Time
 }
 
+// ContextManager collects agent outputs for the current run. It is safe for
+// concurrent use by parallel branches and dag nodes.
 type ContextManager struct {
+	mu      sync.RWMutex
 	History []AgentOutput
 }
 
@@ -23,29 +29,72 @@ func NewContextManager() *ContextManager {
 }
 
 func (cm *ContextManager) AddOutput(agentID string, response string) {
+	cm.AddIterationOutput(agentID, response, 0)
+}
+
+// AddIterationOutput records an output produced inside a loop iteration
+func (cm *ContextManager) AddIterationOutput(agentID string, response string, iteration int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	cm.History = append(cm.History, AgentOutput{
 		AgentID:   agentID,
 		Response:  response,
+		Iteration: iteration,
 		Timestamp: time.Now(),
 	})
 }
 
-func (cm *ContextManager) GetContext() string {
-	if len(cm.History) == 0 {
-		return ""
+// Messages returns the run's outputs as seen by the given agent: its own
+// earlier outputs are assistant turns, and other agents' outputs are user
+// messages labelled with the agent ID.
+func (cm *ContextManager) Messages(agentID string) []types.ChatMessage {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	messages := make([]types.ChatMessage, 0, len(cm.History))
+	for _, output := range cm.History {
+		if output.AgentID == agentID {
+			messages = append(messages, types.ChatMessage{Role: types.RoleAssistant, Content: output.Response})
+			continue
+		}
+
+		label := fmt.Sprintf("[%s]:", output.AgentID)
+		if output.Iteration > 0 {
+			label = fmt.Sprintf("[%s] (iteration %d):", output.AgentID, output.Iteration)
+		}
+		messages = append(messages, types.ChatMessage{
+			Role:    types.RoleUser,
+			Content: label + "\n" + output.Response,
+		})
 	}
+	return messages
+}
 
-	var sb strings.Builder
-	sb.WriteString("Context from previous agents:\n\n")
+// Outputs returns the latest response of each agent, keyed by agent ID
+func (cm *ContextManager) Outputs() map[string]string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 
+	outputs := make(map[string]string, len(cm.History))
 	for _, output := range cm.History {
-		sb.WriteString(fmt.Sprintf("[%s]:\n%s\n\n", output.AgentID, output.Response))
+		outputs[output.AgentID] = output.Response
 	}
+	return outputs
+}
 
-	return sb.String()
+// Snapshot returns a copy of the history
+func (cm *ContextManager) Snapshot() []AgentOutput {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return append([]AgentOutput{}, cm.History...)
 }
 
 func (cm *ContextManager) GetLastOutput() string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
 	if len(cm.History) == 0 {
 		return ""
 	}
@@ -53,5 +102,8 @@ func (cm *ContextManager) GetLastOutput() string {
 }
 
 func (cm *ContextManager) Clear() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	cm.History = []AgentOutput{}
 }
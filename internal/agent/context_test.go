@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestContextMessagesRoles(t *testing.T) {
+	cm := NewContextManager()
+	cm.AddOutput("writer", "draft")
+	cm.AddIterationOutput("critic", "too long", 1)
+
+	messages := cm.Messages("writer")
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].Role != types.RoleAssistant || messages[0].Content != "draft" {
+		t.Errorf("expected the agent's own output as an assistant turn, got %+v", messages[0])
+	}
+	if messages[1].Role != types.RoleUser || messages[1].Content != "[critic] (iteration 1):\ntoo long" {
+		t.Errorf("expected a labelled user message, got %+v", messages[1])
+	}
+}
+
+func TestBuildMessages(t *testing.T) {
+	runner := NewRunner(&types.WorkflowConfig{})
+	runner.SetSessionHistory([]types.ChatMessage{{Role: types.RoleUser, Content: "earlier"}})
+	runner.Context.AddOutput("writer", "draft")
+	agentDef := &types.Agent{ID: "writer", Role: "Writer", Goal: "write it"}
+
+	messages := runner.buildMessages(agentDef, "")
+	want := []types.ChatMessage{
+		{Role: types.RoleSystem, Content: "You are Writer."},
+		{Role: types.RoleUser, Content: "earlier\n\nwrite it"},
+		{Role: types.RoleAssistant, Content: "draft"},
+		{Role: types.RoleUser, Content: "write it"},
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), messages)
+	}
+	for i := range want {
+		if messages[i].Role != want[i].Role || messages[i].Content != want[i].Content {
+			t.Errorf("message %d: expected %+v, got %+v", i, want[i], messages[i])
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// maxErrorBody caps how much of an error response is kept in the message
+const maxErrorBody = 500
+
+// APIError is a model call rejected by the provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Kind       types.ErrorKind
+	RetryAfter time.Duration // Wait requested by the provider, if any
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s api error (%d %s): %s", e.Provider, e.StatusCode, e.Kind, e.Message)
+}
+
+// ErrorKindOf classifies err. Errors that don't come from a provider
+// response, such as dropped connections, are transient.
+func ErrorKindOf(err error) types.ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return types.ErrorTransient
+}
+
+// newAPIError reads and closes a non-200 response and classifies it
+func newAPIError(provider string, resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Kind:       classifyStatus(resp.StatusCode, string(body)),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After"), string(body)),
+		Message:    errorMessage(body),
+	}
+}
+
+// classifyStatus maps an HTTP status and error body to an error kind.
+// Exhausted quotas are told apart from rate limits by the body, since some
+// providers answer both with 429.
+func classifyStatus(status int, body string) types.ErrorKind {
+	lower := strings.ToLower(body)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		strings.Contains(lower, "invalid_api_key") || strings.Contains(lower, "api key not valid"):
+		return types.ErrorAuth
+	case strings.Contains(lower, "insufficient_quota") || strings.Contains(lower, "credit balance"):
+		return types.ErrorQuota
+	case status == http.StatusTooManyRequests:
+		return types.ErrorRateLimited
+	case status == http.StatusRequestTimeout || status == http.StatusConflict || status >= 500:
+		return types.ErrorTransient
+	default:
+		return types.ErrorBadRequest
+	}
+}
+
+// geminiRetryDelay finds the retryDelay Gemini puts in 429 error details
+var geminiRetryDelay = regexp.MustCompile(`"retryDelay":\s*"([0-9.]+s)"`)
+
+// retryAfter reads the wait a provider asked for, from the Retry-After
+// header in seconds or as a date, or from Gemini's error details
+func retryAfter(header, body string) time.Duration {
+	if header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(header); err == nil {
+			if wait := time.Until(at); wait > 0 {
+				return wait
+			}
+		}
+	}
+	if m := geminiRetryDelay.FindStringSubmatch(body); m != nil {
+		if wait, err := time.ParseDuration(m[1]); err == nil {
+			return wait
+		}
+	}
+	return 0
+}
+
+// errorMessage extracts the message of a JSON error body, as sent by
+// OpenAI, Anthropic, Gemini and Ollama, falling back to the raw body
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && len(parsed.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		var text string
+		if json.Unmarshal(parsed.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+		if json.Unmarshal(parsed.Error, &text) == nil && text != "" {
+			return text
+		}
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > maxErrorBody {
+		msg = msg[:maxErrorBody] + "..."
+	}
+	if msg == "" {
+		msg = "empty response"
+	}
+	return msg
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"Orkflow/pkg/types"
+)
+
+// shouldFallback reports whether a failed call is worth sending to another
+// model: quota, auth and availability errors are, requests the provider
+// rejected as malformed are not
+func shouldFallback(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return ErrorKindOf(err) != types.ErrorBadRequest
+}
+
+// fallbackChain returns the models to try after the given one, in order:
+// its fallbacks, each followed by their own, without repeats
+func (r *Runner) fallbackChain(model string) []string {
+	seen := map[string]bool{model: true}
+	var chain []string
+	var walk func(name string)
+	walk = func(name string) {
+		for _, next := range r.Config.Models[name].Fallbacks {
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			chain = append(chain, next)
+			walk(next)
+		}
+	}
+	walk(model)
+	return chain
+}
+
+// fallbackHandler returns the hook moving an agent's conversation down its
+// model's fallback chain, or nil when the model has no fallbacks
+func (r *Runner) fallbackHandler(agentDef *types.Agent, conv *toolConversation) func(err error) bool {
+	chain := r.fallbackChain(agentDef.Model)
+	if len(chain) == 0 {
+		return nil
+	}
+	return func(err error) bool {
+		if !shouldFallback(err) {
+			return false
+		}
+		for len(chain) > 0 {
+			next := chain[0]
+			chain = chain[1:]
+			client, ok := r.Clients[next]
+			if !ok {
+				continue
+			}
+
+			r.endStream(agentDef.ID)
+			fmt.Printf("[%s] ↪️  %s failed (%s), falling back to %s\n", agentDef.ID, conv.model, ErrorKindOf(err), next)
+			if r.Logger != nil {
+				r.Logger.LogAgent(agentDef.ID, "MODEL_FALLBACK", fmt.Sprintf("From: %s, To: %s, Error: %v", conv.model, next, err))
+			}
+			conv.switchModel(next, withParams(client, r.paramsFor(next, agentDef)), r.retryPolicyFor(next, agentDef))
+			return true
+		}
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+// recordedCall is one call seen by statsRecorder
+type recordedCall struct {
+	agentID, provider, model string
+}
+
+type statsRecorder struct {
+	calls []recordedCall
+}
+
+func (s *statsRecorder) StartAgent(agentID, role, provider, model string) {}
+func (s *statsRecorder) RecordCall(agentID, provider, model string, usage types.Usage, duration time.Duration) {
+	s.calls = append(s.calls, recordedCall{agentID, provider, model})
+}
+func (s *statsRecorder) CompleteAgent(agentID string, duration time.Duration) {}
+
+func TestRunAgentFallsBack(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"type":"insufficient_quota","message":"You exceeded your current quota"}}`))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from backup"}}]}`))
+	}))
+	defer backup.Close()
+
+	config := &types.WorkflowConfig{
+		Models: map[string]types.Model{
+			"main":   {Provider: "openai", Model: "gpt-4o", APIKey: "key", Endpoint: primary.URL, Fallbacks: []string{"gone", "backup"}},
+			"gone":   {Provider: "openai", Model: "gpt-4o", APIKey: "key", Endpoint: "http://127.0.0.1:1"},
+			"backup": {Provider: "openai", Model: "gpt-4o-mini", APIKey: "key", Endpoint: backup.URL},
+		},
+		Agents: []types.Agent{{ID: "writer", Model: "main", Retry: &types.RetryConfig{InitialDelay: time.Millisecond}}},
+	}
+	runner := NewRunner(config)
+	stats := &statsRecorder{}
+	runner.Stats = stats
+	var messages []memory.Message
+	runner.MessageCallback = func(msg memory.Message) { messages = append(messages, msg) }
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "from backup" {
+		t.Errorf("expected the backup's reply, got %q", response)
+	}
+	if len(stats.calls) != 1 || stats.calls[0].model != "gpt-4o-mini" {
+		t.Errorf("expected one call recorded under the backup model, got %+v", stats.calls)
+	}
+	if len(messages) != 1 || messages[0].Model != "openai/gpt-4o-mini" {
+		t.Errorf("expected the message to name the backup model, got %+v", messages)
+	}
+}
+
+func TestRunAgentNoFallbackOnBadRequest(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid schema"}}`))
+	}))
+	defer server.Close()
+
+	config := &types.WorkflowConfig{
+		Models: map[string]types.Model{
+			"main":   {Provider: "openai", Model: "gpt-4o", APIKey: "key", Endpoint: server.URL, Fallbacks: []string{"backup"}},
+			"backup": {Provider: "openai", Model: "gpt-4o-mini", APIKey: "key", Endpoint: server.URL},
+		},
+		Agents: []types.Agent{{ID: "writer", Model: "main"}},
+	}
+	runner := NewRunner(config)
+
+	if _, err := runner.RunAgent(context.Background(), &config.Agents[0]); err == nil {
+		t.Fatal("expected the bad request to fail the agent")
+	}
+	if requests != 1 {
+		t.Errorf("expected a single request, got %d", requests)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	runner := NewRunner(&types.WorkflowConfig{Models: map[string]types.Model{
+		"a": {Fallbacks: []string{"b", "c"}},
+		"b": {Fallbacks: []string{"d", "a"}},
+		"c": {},
+		"d": {Fallbacks: []string{"c"}},
+	}})
+	got := runner.fallbackChain("a")
+	want := []string{"b", "d", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
@@ -2,71 +2,326 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
+
+	"Orkflow/pkg/types"
 )
 
+const geminiEndpoint = "https://generativelanguage.googleapis.com"
+
 type GeminiClient struct {
-	APIKey string
-	Model  string
+	APIKey   string
+	Model    string
+	Endpoint string                 // API base URL, defaults to the Gemini API
+	Params   types.GenerationParams // Sent with every request
 }
 
-func (g *GeminiClient) Generate(prompt string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1/models/%s:generateContent?key=%s", g.Model, g.APIKey)
+// WithParams returns a copy of the client sending the given parameters
+func (g *GeminiClient) WithParams(params types.GenerationParams) LLMClient {
+	client := *g
+	client.Params = params
+	return &client
+}
 
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
+func (g *GeminiClient) Generate(ctx context.Context, prompt string) (string, error) {
+	reply, err := g.Chat(ctx, []types.ChatMessage{{Role: types.RoleUser, Content: prompt}})
+	return reply.Content, err
+}
+
+// Chat sends a conversation and returns the model's text reply
+func (g *GeminiClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	return g.GenerateWithTools(ctx, messages, nil)
+}
+
+// GenerateWithTools sends a conversation with function declarations and
+// returns the model's reply, which may contain function calls. Gemini does
+// not assign call IDs, so calls are numbered in order.
+func (g *GeminiClient) GenerateWithTools(ctx context.Context, messages []types.ChatMessage, tools []ToolSpec) (types.ChatMessage, error) {
+	payload := geminiPayload(messages, g.Params)
+	if len(tools) > 0 {
+		decls := make([]map[string]interface{}, 0, len(tools))
+		for _, tool := range tools {
+			decls = append(decls, map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  geminiSchema(tool.Parameters),
+			})
+		}
+		payload["tools"] = []map[string]interface{}{{"functionDeclarations": decls}}
+		// Gemini rejects JSON output modes alongside function calling, so
+		// structured replies rely on the prompt while tools are offered
+		if config, ok := payload["generationConfig"].(map[string]interface{}); ok {
+			delete(config, "responseMimeType")
+			delete(config, "responseSchema")
+		}
+	}
+
+	// System instructions and function calling are served by the v1beta API
+	var result struct {
+		Candidates []struct {
+			Content geminiContent `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata *geminiUsage `json:"usageMetadata"`
+	}
+	if err := g.post(ctx, "v1beta", payload, &result); err != nil {
+		return types.ChatMessage{}, err
+	}
+
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return types.ChatMessage{}, fmt.Errorf("no response from gemini")
+	}
+
+	reply := types.ChatMessage{Role: "assistant", Usage: result.UsageMetadata.toUsage()}
+	var text []string
+	for _, part := range result.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			reply.ToolCalls = append(reply.ToolCalls, types.ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(reply.ToolCalls)+1),
+				Name:      part.FunctionCall.Name,
+				Arguments: part.FunctionCall.Args,
+			})
+		} else if part.Text != "" {
+			text = append(text, part.Text)
+		}
+	}
+	reply.Content = strings.Join(text, "")
+	return reply, nil
+}
+
+// GenerateStream streams a completion from streamGenerateContent over
+// server-sent events. Each chunk reports the usage so far, so the last one
+// wins.
+func (g *GeminiClient) GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error) {
+	resp, err := g.send(ctx, "v1beta", "streamGenerateContent", "&alt=sse", geminiPayload(messages, g.Params))
+	if err != nil {
+		return types.ChatMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	reply := types.ChatMessage{Role: types.RoleAssistant}
+	var sb strings.Builder
+	err = readSSE(resp.Body, func(data string) error {
+		var chunk struct {
+			Candidates []struct {
+				Content geminiContent `json:"content"`
+			} `json:"candidates"`
+			UsageMetadata *geminiUsage `json:"usageMetadata"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("gemini: invalid stream chunk: %w", err)
+		}
+		if chunk.UsageMetadata != nil {
+			reply.Usage = chunk.UsageMetadata.toUsage()
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Text != "" {
+				sb.WriteString(part.Text)
+				onToken(part.Text)
+			}
+		}
+		return nil
+	})
+	reply.Content = sb.String()
+	return reply, err
+}
+
+// post sends a generateContent request to the given API version and decodes
+// the response into result
+func (g *GeminiClient) post(ctx context.Context, version string, payload interface{}, result interface{}) error {
+	resp, err := g.send(ctx, version, "generateContent", "", payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// send posts to a model method of the given API version, returning an error
+// for non-200 responses. query is appended to the URL after the API key.
+func (g *GeminiClient) send(ctx context.Context, version, method, query string, payload interface{}) (*http.Response, error) {
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = geminiEndpoint
 	}
+	url := fmt.Sprintf("%s/%s/models/%s:%s?key=%s%s", strings.TrimSuffix(endpoint, "/"), version, g.Model, method, g.APIKey, query)
 
 	body, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("gemini", resp)
+	}
 
-		// Check for quota exceeded (429) - prefix for detection
-		if resp.StatusCode == http.StatusTooManyRequests {
-			return "", fmt.Errorf("QUOTA_EXCEEDED[%s]: quota limit reached", g.Model)
-		}
+	return resp, nil
+}
 
-		return "", fmt.Errorf("gemini api error: %s", string(respBody))
+// geminiPayload builds a generateContent request body for a conversation
+func geminiPayload(messages []types.ChatMessage, params types.GenerationParams) map[string]interface{} {
+	system, contents := toGeminiContents(messages)
+	payload := map[string]interface{}{
+		"contents": contents,
+	}
+	if system != "" {
+		payload["systemInstruction"] = geminiContent{Parts: []geminiPart{{Text: system}}}
 	}
+	if config := geminiGenerationConfig(params); len(config) > 0 {
+		payload["generationConfig"] = config
+	}
+	return payload
+}
 
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+// geminiGenerationConfig maps generation parameters to a generationConfig
+func geminiGenerationConfig(params types.GenerationParams) map[string]interface{} {
+	config := make(map[string]interface{})
+	if params.Temperature != nil {
+		config["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		config["topP"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		config["maxOutputTokens"] = params.MaxTokens
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if len(params.Stop) > 0 {
+		config["stopSequences"] = params.Stop
 	}
+	if params.Seed != nil {
+		config["seed"] = *params.Seed
+	}
+	if params.ResponseFormat == types.ResponseFormatJSON || params.Schema != nil {
+		config["responseMimeType"] = "application/json"
+	}
+	if params.Schema != nil {
+		config["responseSchema"] = geminiSchema(params.Schema)
+	}
+	return config
+}
 
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response from gemini")
+// geminiUsage is the usageMetadata block of a generateContent response
+type geminiUsage struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"` // Thinking models bill these as output
+}
+
+// toUsage converts the block, returning nil when the response had none
+func (u *geminiUsage) toUsage() *types.Usage {
+	if u == nil {
+		return nil
+	}
+	return &types.Usage{
+		InputTokens:       u.PromptTokenCount,
+		CachedInputTokens: u.CachedContentTokenCount,
+		OutputTokens:      u.CandidatesTokenCount + u.ThoughtsTokenCount,
+	}
+}
+
+// geminiContent is a turn in the Gemini wire format
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// toGeminiContents converts a conversation to the Gemini format. Assistant
+// turns use the "model" role, and consecutive tool results are merged into
+// one turn of function responses.
+func toGeminiContents(messages []types.ChatMessage) (string, []geminiContent) {
+	var system []string
+	var result []geminiContent
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "tool":
+			part := geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     msg.Name,
+				Response: map[string]interface{}{"result": msg.Content},
+			}}
+			if n := len(result); n > 0 && len(result[n-1].Parts) > 0 && result[n-1].Parts[0].FunctionResponse != nil {
+				result[n-1].Parts = append(result[n-1].Parts, part)
+				continue
+			}
+			result = append(result, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		default:
+			out := geminiContent{Role: "user"}
+			if msg.Role == "assistant" {
+				out.Role = "model"
+			}
+			if msg.Content != "" {
+				out.Parts = append(out.Parts, geminiPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				out.Parts = append(out.Parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: call.Arguments}})
+			}
+			result = append(result, out)
+		}
 	}
 
-	return result.Candidates[0].Content.Parts[0].Text, nil
+	return strings.Join(system, "\n\n"), result
+}
+
+// geminiSchema keeps the subset of JSON Schema that Gemini function
+// declarations accept. Keys such as "$schema" or "additionalProperties",
+// common in MCP tool schemas, are rejected by the API.
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range schema {
+		switch key {
+		case "type", "description", "required", "enum", "format", "nullable":
+			result[key] = value
+		case "items":
+			if items, ok := value.(map[string]interface{}); ok {
+				result[key] = geminiSchema(items)
+			}
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			converted := make(map[string]interface{}, len(props))
+			for name, prop := range props {
+				if propSchema, ok := prop.(map[string]interface{}); ok {
+					converted[name] = geminiSchema(propSchema)
+				}
+			}
+			result[key] = converted
+		}
+	}
+	return result
 }
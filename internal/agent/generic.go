@@ -2,11 +2,15 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+
+	"Orkflow/pkg/types"
 )
 
 // Known OpenAI-compatible API endpoints
@@ -26,6 +30,14 @@ type GenericClient struct {
 	Model    string
 	Endpoint string
 	Provider string
+	Params   types.GenerationParams // Sent with every request
+}
+
+// WithParams returns a copy of the client sending the given parameters
+func (g *GenericClient) WithParams(params types.GenerationParams) LLMClient {
+	client := *g
+	client.Params = params
+	return &client
 }
 
 // NewGenericClient creates a client for any OpenAI-compatible API
@@ -49,65 +61,117 @@ func NewGenericClient(provider, model, apiKey, endpoint string) *GenericClient {
 	}
 }
 
-func (g *GenericClient) Generate(prompt string) (string, error) {
-	payload := map[string]interface{}{
-		"model": g.Model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-	}
+func (g *GenericClient) Generate(ctx context.Context, prompt string) (string, error) {
+	reply, err := g.Chat(ctx, []types.ChatMessage{{Role: types.RoleUser, Content: prompt}})
+	return reply.Content, err
+}
 
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", g.Endpoint, bytes.NewReader(body))
-	if err != nil {
-		return "", err
+// Chat sends a conversation in the OpenAI format and returns the reply
+func (g *GenericClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	payload := map[string]interface{}{
+		"model":    g.Model,
+		"messages": toOpenAIMessages(messages),
 	}
+	setOpenAIParams(payload, g.Params, "max_tokens")
 
-	req.Header.Set("Authorization", "Bearer "+g.APIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := g.send(ctx, payload)
 	if err != nil {
-		return "", fmt.Errorf("%s connection error: %w", g.Provider, err)
+		return types.ChatMessage{}, err
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		// Check for common errors
-		errStr := string(respBody)
-		if strings.Contains(errStr, "invalid_api_key") || strings.Contains(errStr, "Unauthorized") {
-			return "", fmt.Errorf("%s: invalid API key", g.Provider)
-		}
-		if strings.Contains(errStr, "rate_limit") || strings.Contains(errStr, "quota") {
-			return "", fmt.Errorf("QUOTA_EXCEEDED[%s]: rate limit reached", g.Provider)
-		}
-		return "", fmt.Errorf("%s API error (%d): %s", g.Provider, resp.StatusCode, errStr)
-	}
-
 	var result struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
 		Error struct {
 			Message string `json:"message"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("%s: failed to parse response: %w", g.Provider, err)
+		return types.ChatMessage{}, fmt.Errorf("%s: failed to parse response: %w", g.Provider, err)
 	}
 
 	if result.Error.Message != "" {
-		return "", fmt.Errorf("%s error: %s", g.Provider, result.Error.Message)
+		return types.ChatMessage{}, fmt.Errorf("%s error: %s", g.Provider, result.Error.Message)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response from %s", g.Provider)
+		return types.ChatMessage{}, fmt.Errorf("no response from %s", g.Provider)
+	}
+
+	return types.ChatMessage{
+		Role:    types.RoleAssistant,
+		Content: result.Choices[0].Message.Content,
+		Usage:   result.Usage.toUsage(),
+	}, nil
+}
+
+// GenerateStream streams a completion over server-sent events, asking for
+// the usage block OpenAI sends at the end of a stream
+func (g *GenericClient) GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error) {
+	payload := map[string]interface{}{
+		"model":          g.Model,
+		"messages":       toOpenAIMessages(messages),
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
+	}
+	setOpenAIParams(payload, g.Params, "max_tokens")
+
+	resp, err := g.send(ctx, payload)
+	if err != nil {
+		return types.ChatMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	reply, err := readOpenAIStream(resp.Body, onToken)
+	if err != nil {
+		return reply, fmt.Errorf("%s: %w", g.Provider, err)
+	}
+	if reply.Usage == nil {
+		warnNoStreamUsage(g.Provider)
+	}
+	return reply, nil
+}
+
+// providersWithoutUsage holds the providers already warned about streams
+// without token usage
+var providersWithoutUsage sync.Map
+
+// warnNoStreamUsage warns, once per provider, that streamed calls are not
+// counted towards costs and budgets
+func warnNoStreamUsage(provider string) {
+	if _, warned := providersWithoutUsage.LoadOrStore(provider, true); !warned {
+		fmt.Printf("⚠️  %s does not report token usage when streaming; its calls count as 0 tokens (use --no-stream to count them)\n", provider)
+	}
+}
+
+// send posts a chat completions request, mapping error responses to
+// readable errors
+func (g *GenericClient) send(ctx context.Context, payload interface{}) (*http.Response, error) {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", g.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+g.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s connection error: %w", g.Provider, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(g.Provider, resp)
 	}
 
-	return result.Choices[0].Message.Content, nil
+	return resp, nil
 }
@@ -1,25 +1,103 @@
 package agent
 
+import (
+	"context"
+	"strings"
+
+	"Orkflow/pkg/types"
+)
+
 type LLMClient interface {
-	Generate(prompt string) (string, error)
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// ChatClient is implemented by every provider client. It sends a
+// conversation of system, user, assistant and tool messages, so the agent's
+// persona, earlier outputs and tool exchanges keep their roles. Clients
+// without it receive the conversation flattened into one prompt. The reply
+// carries the token usage the provider reported, if any.
+type ChatClient interface {
+	Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error)
+}
+
+// Streamer is implemented by clients that can stream a reply. onToken
+// receives each chunk of text as it arrives; the full reply is still returned.
+type Streamer interface {
+	GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error)
+}
+
+// ToolCaller is implemented by clients whose provider has a native
+// tool-calling API. The runner prefers it over the ```tool: fence format.
+type ToolCaller interface {
+	GenerateWithTools(ctx context.Context, messages []types.ChatMessage, tools []ToolSpec) (types.ChatMessage, error)
+}
+
+// Tunable is implemented by clients that accept generation parameters such
+// as temperature and max tokens. WithParams returns a copy of the client
+// that sends them with every request.
+type Tunable interface {
+	WithParams(params types.GenerationParams) LLMClient
+}
+
+// withParams applies generation parameters to a client that accepts them
+func withParams(client LLMClient, params types.GenerationParams) LLMClient {
+	if tunable, ok := client.(Tunable); ok {
+		return tunable.WithParams(params)
+	}
+	return client
+}
+
+// flattenMessages joins a conversation into one prompt for clients that
+// only implement Generate
+func flattenMessages(messages []types.ChatMessage) string {
+	parts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Content != "" {
+			parts = append(parts, msg.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// mergeTurns joins consecutive plain user or assistant messages into one
+// turn. Gemini rejects conversations whose roles do not alternate.
+func mergeTurns(messages []types.ChatMessage) []types.ChatMessage {
+	result := make([]types.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(result); n > 0 && isPlainTurn(msg) && isPlainTurn(result[n-1]) && result[n-1].Role == msg.Role {
+			result[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+// isPlainTurn reports whether a message is user or assistant text without
+// tool calls
+func isPlainTurn(msg types.ChatMessage) bool {
+	return (msg.Role == types.RoleUser || msg.Role == types.RoleAssistant) && len(msg.ToolCalls) == 0
 }
 
 func NewLLMClient(provider string, model string, apiKey string, endpoint string) LLMClient {
 	switch provider {
 	case "anthropic":
 		return &ClaudeClient{
-			APIKey: apiKey,
-			Model:  model,
+			APIKey:   apiKey,
+			Model:    model,
+			Endpoint: endpoint,
 		}
 	case "openai":
 		return &OpenAIClient{
-			APIKey: apiKey,
-			Model:  model,
+			APIKey:   apiKey,
+			Model:    model,
+			Endpoint: endpoint,
 		}
 	case "gemini", "google":
 		return &GeminiClient{
-			APIKey: apiKey,
-			Model:  model,
+			APIKey:   apiKey,
+			Model:    model,
+			Endpoint: endpoint,
 		}
 	case "ollama":
 		ep := endpoint
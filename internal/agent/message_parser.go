@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"encoding/json"
 	"regexp"
 	"strings"
 )
@@ -13,11 +14,19 @@ type OutgoingMessage struct {
 
 var (
 	// Regex patterns for parsing message tags from LLM responses
-	messagePattern   = regexp.MustCompile(`(?s)<message\s+to="([^"]+)">(.*?)</message>`)
-	broadcastPattern = regexp.MustCompile(`(?s)<broadcast>(.*?)</broadcast>`)
-	donePattern      = regexp.MustCompile(`<DONE\s*/>`)
+	messagePattern    = regexp.MustCompile(`(?s)<message\s+to="([^"]+)">(.*?)</message>`)
+	broadcastPattern  = regexp.MustCompile(`(?s)<broadcast>(.*?)</broadcast>`)
+	donePattern       = regexp.MustCompile(`<DONE\s*/>`)
+	jsonObjectPattern = regexp.MustCompile(`(?s)\{[^{}]*\}`)
+	delegatePattern   = regexp.MustCompile(`(?s)<delegate\s+to="([^"]+)">(.*?)</delegate>`)
 )
 
+// Delegation is a task a supervisor hands to one of its sub-agents
+type Delegation struct {
+	To   string // Sub-agent ID
+	Task string // Task description
+}
+
 // ParseOutgoingMessages extracts messages from an LLM response.
 // Supports:
 //   - <message to="agent_id">content</message> - Direct message
@@ -51,6 +60,24 @@ func ParseOutgoingMessages(response string) []OutgoingMessage {
 	return messages
 }
 
+// ParseDelegations extracts <delegate to="agent_id">task</delegate>
+// directives from a supervisor response, in order.
+func ParseDelegations(response string) []Delegation {
+	var delegations []Delegation
+	for _, match := range delegatePattern.FindAllStringSubmatch(response, -1) {
+		delegations = append(delegations, Delegation{
+			To:   strings.TrimSpace(match[1]),
+			Task: strings.TrimSpace(match[2]),
+		})
+	}
+	return delegations
+}
+
+// StripDelegations removes delegate directives from a response
+func StripDelegations(response string) string {
+	return strings.TrimSpace(delegatePattern.ReplaceAllString(response, ""))
+}
+
 // ContainsDoneSignal checks if the response contains a <DONE/> signal
 func ContainsDoneSignal(response string) bool {
 	return donePattern.MatchString(response)
@@ -86,3 +113,41 @@ func ExtractFinalOutput(conversation []string) string {
 	}
 	return strings.TrimSpace(sb.String())
 }
+
+// ParseRoute extracts the route a router agent chose from its response.
+// It looks for a JSON object like {"route": "approve"} first, and falls back
+// to a line that names one of the routes on its own. Matching is
+// case-insensitive; the returned route uses the spelling from routes.
+func ParseRoute(response string, routes []string) (string, bool) {
+	match := func(candidate string) (string, bool) {
+		candidate = strings.Trim(strings.TrimSpace(candidate), "\"'`*.:")
+		for _, route := range routes {
+			if strings.EqualFold(candidate, route) {
+				return route, true
+			}
+		}
+		return "", false
+	}
+
+	for _, obj := range jsonObjectPattern.FindAllString(response, -1) {
+		var reply struct {
+			Route string `json:"route"`
+		}
+		if err := json.Unmarshal([]byte(obj), &reply); err == nil && reply.Route != "" {
+			if route, ok := match(reply.Route); ok {
+				return route, true
+			}
+		}
+	}
+
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 6 && strings.EqualFold(line[:6], "route:") {
+			line = line[6:]
+		}
+		if route, ok := match(line); ok {
+			return route, true
+		}
+	}
+	return "", false
+}
@@ -174,3 +174,64 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParseRoute_JSON(t *testing.T) {
+	routes := []string{"approve", "reject"}
+
+	response := "The patch breaks the build.\n\n```json\n{\"route\": \"reject\", \"reason\": \"tests fail\"}\n```"
+	route, ok := ParseRoute(response, routes)
+	if !ok || route != "reject" {
+		t.Errorf("expected reject, got %q (ok=%v)", route, ok)
+	}
+
+	route, ok = ParseRoute(`{"route": "APPROVE"}`, routes)
+	if !ok || route != "approve" {
+		t.Errorf("expected case-insensitive match, got %q (ok=%v)", route, ok)
+	}
+}
+
+func TestParseRoute_PlainLine(t *testing.T) {
+	routes := []string{"approve", "reject"}
+
+	route, ok := ParseRoute("Reviewed everything.\nRoute: approve", routes)
+	if !ok || route != "approve" {
+		t.Errorf("expected approve, got %q (ok=%v)", route, ok)
+	}
+}
+
+func TestParseRoute_NoMatch(t *testing.T) {
+	routes := []string{"approve", "reject"}
+
+	if route, ok := ParseRoute(`{"route": "maybe"}`, routes); ok {
+		t.Errorf("expected no route, got %q", route)
+	}
+	if route, ok := ParseRoute("I would approve this with changes", routes); ok {
+		t.Errorf("a route inside a sentence should not match, got %q", route)
+	}
+}
+
+func TestParseDelegations(t *testing.T) {
+	response := `Let me split this up.
+<delegate to="researcher">Find recent papers on RAG</delegate>
+<delegate to="coder">
+Write a retrieval benchmark
+</delegate>`
+
+	delegations := ParseDelegations(response)
+	if len(delegations) != 2 {
+		t.Fatalf("expected 2 delegations, got %d", len(delegations))
+	}
+	if delegations[0].To != "researcher" || delegations[0].Task != "Find recent papers on RAG" {
+		t.Errorf("unexpected first delegation: %+v", delegations[0])
+	}
+	if delegations[1].To != "coder" || delegations[1].Task != "Write a retrieval benchmark" {
+		t.Errorf("unexpected second delegation: %+v", delegations[1])
+	}
+
+	if got := StripDelegations(response); got != "Let me split this up." {
+		t.Errorf("StripDelegations() = %q", got)
+	}
+	if len(ParseDelegations("Final answer: 42")) != 0 {
+		t.Error("expected no delegations in a final answer")
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"Orkflow/pkg/types"
 )
 
 const OllamaTimeout = 3 * time.Minute // Max time for generation
@@ -15,25 +17,153 @@ const OllamaTimeout = 3 * time.Minute // Max time for generation
 type OllamaClient struct {
 	Endpoint string
 	Model    string
+	Params   types.GenerationParams // Sent with every request
 }
 
-func (o *OllamaClient) Generate(prompt string) (string, error) {
-	payload := map[string]interface{}{
-		"model":  o.Model,
-		"prompt": prompt,
-		"stream": false,
+// WithParams returns a copy of the client sending the given parameters
+func (o *OllamaClient) WithParams(params types.GenerationParams) LLMClient {
+	client := *o
+	client.Params = params
+	return &client
+}
+
+// ollamaMessage is a chat message in the Ollama wire format
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (o *OllamaClient) Generate(ctx context.Context, prompt string) (string, error) {
+	reply, err := o.Chat(ctx, []types.ChatMessage{{Role: types.RoleUser, Content: prompt}})
+	return reply.Content, err
+}
+
+// Chat sends a conversation to /api/chat and returns the reply
+func (o *OllamaClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, OllamaTimeout)
+	defer cancel()
+
+	resp, err := o.send(ctx, messages, false)
+	if err != nil {
+		return types.ChatMessage{}, err
 	}
+	defer resp.Body.Close()
 
-	body, _ := json.Marshal(payload)
-	url := o.Endpoint + "/api/generate"
+	var result ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return types.ChatMessage{}, err
+	}
+
+	return types.ChatMessage{
+		Role:    types.RoleAssistant,
+		Content: result.Message.Content,
+		Usage:   result.usage(),
+	}, nil
+}
 
-	// Create request with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), OllamaTimeout)
+// GenerateStream streams a reply from Ollama's newline-delimited JSON
+// responses. Token counts arrive with the final chunk.
+func (o *OllamaClient) GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, OllamaTimeout)
 	defer cancel()
 
+	resp, err := o.send(ctx, messages, true)
+	if err != nil {
+		return types.ChatMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	reply := types.ChatMessage{Role: types.RoleAssistant}
+	var sb strings.Builder
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("ollama: invalid stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama api error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			sb.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		if chunk.Done {
+			reply.Usage = chunk.usage()
+		}
+		return nil
+	})
+	reply.Content = sb.String()
+	return reply, err
+}
+
+// ollamaResponse is a chat response, or one chunk of a streamed response
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// usage returns the token counts of a finished response, or nil when
+// Ollama did not report any
+func (r *ollamaResponse) usage() *types.Usage {
+	if r.PromptEvalCount == 0 && r.EvalCount == 0 {
+		return nil
+	}
+	return &types.Usage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount}
+}
+
+// ollamaOptions maps generation parameters to Ollama model options
+func ollamaOptions(params types.GenerationParams) map[string]interface{} {
+	options := make(map[string]interface{})
+	if params.Temperature != nil {
+		options["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		options["top_p"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		options["num_predict"] = params.MaxTokens
+	}
+	if len(params.Stop) > 0 {
+		options["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		options["seed"] = *params.Seed
+	}
+	return options
+}
+
+// send posts a chat request, returning an error for non-200 responses.
+// ctx carries the generation timeout.
+func (o *OllamaClient) send(ctx context.Context, messages []types.ChatMessage, stream bool) (*http.Response, error) {
+	converted := make([]ollamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		converted = append(converted, ollamaMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	payload := map[string]interface{}{
+		"model":    o.Model,
+		"messages": converted,
+		"stream":   stream,
+	}
+	if options := ollamaOptions(o.Params); len(options) > 0 {
+		payload["options"] = options
+	}
+	if o.Params.Schema != nil {
+		// Ollama constrains the output to a schema passed as the format
+		payload["format"] = o.Params.Schema
+	} else if o.Params.ResponseFormat == types.ResponseFormatJSON {
+		payload["format"] = "json"
+	}
+
+	body, _ := json.Marshal(payload)
+	url := o.Endpoint + "/api/chat"
+
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -41,23 +171,14 @@ func (o *OllamaClient) Generate(prompt string) (string, error) {
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("TIMEOUT: Ollama generation exceeded %v (try a faster model or shorter prompt)", OllamaTimeout)
+			return nil, fmt.Errorf("TIMEOUT: Ollama generation exceeded %v (try a faster model or shorter prompt)", OllamaTimeout)
 		}
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama api error: %s", string(respBody))
-	}
-
-	var result struct {
-		Response string `json:"response"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, newAPIError("ollama", resp)
 	}
 
-	return result.Response, nil
+	return resp, nil
 }
@@ -2,29 +2,116 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+
+	"Orkflow/pkg/types"
 )
 
+const openAIEndpoint = "https://api.openai.com/v1/chat/completions"
+
 type OpenAIClient struct {
-	APIKey string
-	Model  string
+	APIKey   string
+	Model    string
+	Endpoint string                 // Chat completions URL, defaults to the OpenAI API
+	Params   types.GenerationParams // Sent with every request
 }
 
-func (o *OpenAIClient) Generate(prompt string) (string, error) {
+// WithParams returns a copy of the client sending the given parameters
+func (o *OpenAIClient) WithParams(params types.GenerationParams) LLMClient {
+	client := *o
+	client.Params = params
+	return &client
+}
+
+func (o *OpenAIClient) Generate(ctx context.Context, prompt string) (string, error) {
+	reply, err := o.Chat(ctx, []types.ChatMessage{{Role: types.RoleUser, Content: prompt}})
+	return reply.Content, err
+}
+
+// Chat sends a conversation and returns the assistant's reply
+func (o *OpenAIClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	return o.GenerateWithTools(ctx, messages, nil)
+}
+
+// GenerateWithTools sends a conversation with function tools and returns the
+// assistant's reply, which may request tool calls
+func (o *OpenAIClient) GenerateWithTools(ctx context.Context, messages []types.ChatMessage, tools []ToolSpec) (types.ChatMessage, error) {
 	payload := map[string]interface{}{
-		"model": o.Model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    o.Model,
+		"messages": toOpenAIMessages(messages),
+	}
+	setOpenAIParams(payload, o.Params, "max_completion_tokens")
+	setOpenAISchema(payload, o.Params)
+	if len(tools) > 0 {
+		payload["tools"] = toOpenAITools(tools)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message openAIMessage `json:"message"`
+		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
+	}
+	if err := o.post(ctx, payload, &result); err != nil {
+		return types.ChatMessage{}, err
+	}
+
+	if len(result.Choices) == 0 {
+		return types.ChatMessage{}, fmt.Errorf("no response from openai")
+	}
+
+	reply, err := fromOpenAIMessage(result.Choices[0].Message)
+	reply.Usage = result.Usage.toUsage()
+	return reply, err
+}
+
+// GenerateStream streams a completion over server-sent events
+func (o *OpenAIClient) GenerateStream(ctx context.Context, messages []types.ChatMessage, onToken func(token string)) (types.ChatMessage, error) {
+	payload := map[string]interface{}{
+		"model":          o.Model,
+		"messages":       toOpenAIMessages(messages),
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
+	}
+	setOpenAIParams(payload, o.Params, "max_completion_tokens")
+	setOpenAISchema(payload, o.Params)
+
+	resp, err := o.send(ctx, payload)
+	if err != nil {
+		return types.ChatMessage{}, err
+	}
+	defer resp.Body.Close()
+
+	return readOpenAIStream(resp.Body, onToken)
+}
+
+// post sends a chat completions request and decodes the response into result
+func (o *OpenAIClient) post(ctx context.Context, payload interface{}, result interface{}) error {
+	resp, err := o.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// send posts a chat completions request, returning an error for non-200 responses
+func (o *OpenAIClient) send(ctx context.Context, payload interface{}) (*http.Response, error) {
+	endpoint := o.Endpoint
+	if endpoint == "" {
+		endpoint = openAIEndpoint
 	}
 
 	body, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+o.APIKey)
@@ -32,29 +119,181 @@ func (o *OpenAIClient) Generate(prompt string) (string, error) {
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("openai api error: %s", string(respBody))
+		return nil, newAPIError("openai", resp)
 	}
 
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	return resp, nil
+}
+
+// setOpenAIParams adds generation parameters to a chat completions request.
+// OpenAI takes max_completion_tokens; compatible APIs still expect max_tokens.
+func setOpenAIParams(payload map[string]interface{}, params types.GenerationParams, maxTokensKey string) {
+	if params.Temperature != nil {
+		payload["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		payload["top_p"] = *params.TopP
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if params.MaxTokens > 0 {
+		payload[maxTokensKey] = params.MaxTokens
 	}
+	if len(params.Stop) > 0 {
+		payload["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		payload["seed"] = *params.Seed
+	}
+	if params.ResponseFormat == types.ResponseFormatJSON || params.Schema != nil {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
+}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response from openai")
+// setOpenAISchema asks for structured output following the reply schema.
+// Compatible APIs only get JSON mode, which more of them understand.
+func setOpenAISchema(payload map[string]interface{}, params types.GenerationParams) {
+	if params.Schema == nil {
+		return
 	}
+	payload["response_format"] = map[string]interface{}{
+		"type":        "json_schema",
+		"json_schema": map[string]interface{}{"name": "output", "schema": params.Schema},
+	}
+}
+
+// readOpenAIStream collects the content deltas of a chat completions stream,
+// and the usage of its final chunk when the server sends one. It is shared
+// by all OpenAI-compatible clients.
+func readOpenAIStream(body io.Reader, onToken func(token string)) (types.ChatMessage, error) {
+	reply := types.ChatMessage{Role: types.RoleAssistant}
+	var sb strings.Builder
+	err := readSSE(body, func(data string) error {
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *openAIUsage `json:"usage"`
+			XGroq *struct {
+				Usage *openAIUsage `json:"usage"`
+			} `json:"x_groq"` // Groq reports usage here instead
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("stream error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			reply.Usage = chunk.Usage.toUsage()
+		} else if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+			reply.Usage = chunk.XGroq.Usage.toUsage()
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			token := chunk.Choices[0].Delta.Content
+			sb.WriteString(token)
+			onToken(token)
+		}
+		return nil
+	})
+	reply.Content = sb.String()
+	return reply, err
+}
 
-	return result.Choices[0].Message.Content, nil
+// openAIUsage is the token usage block of a chat completions response
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// toUsage converts the block, returning nil when the response had none
+func (u *openAIUsage) toUsage() *types.Usage {
+	if u == nil {
+		return nil
+	}
+	return &types.Usage{
+		InputTokens:       u.PromptTokens,
+		CachedInputTokens: u.PromptTokensDetails.CachedTokens,
+		OutputTokens:      u.CompletionTokens,
+	}
+}
+
+// openAIMessage is a chat message in the OpenAI wire format
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded object
+	} `json:"function"`
+}
+
+func toOpenAIMessages(messages []types.ChatMessage) []openAIMessage {
+	result := make([]openAIMessage, 0, len(messages))
+	for _, msg := range messages {
+		out := openAIMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, call := range msg.ToolCalls {
+			args, _ := json.Marshal(call.Arguments)
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(args)
+			out.ToolCalls = append(out.ToolCalls, tc)
+		}
+		result = append(result, out)
+	}
+	return result
+}
+
+func toOpenAITools(tools []ToolSpec) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.Parameters,
+			},
+		})
+	}
+	return result
+}
+
+func fromOpenAIMessage(msg openAIMessage) (types.ChatMessage, error) {
+	reply := types.ChatMessage{Role: "assistant", Content: msg.Content}
+	for _, tc := range msg.ToolCalls {
+		var args map[string]interface{}
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return types.ChatMessage{}, fmt.Errorf("openai: invalid arguments for tool %s: %w", tc.Function.Name, err)
+			}
+		}
+		reply.ToolCalls = append(reply.ToolCalls, types.ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+	return reply, nil
 }
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestRunAgentSendsGenerationParams(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer server.Close()
+
+	zero, warm, seed := 0.0, 0.9, 7
+	config := toolTestConfig("openai", server.URL)
+	model := config.Models["m"]
+	model.GenerationParams = types.GenerationParams{Temperature: &warm, MaxTokens: 256, Seed: &seed}
+	config.Models["m"] = model
+	config.Agents[0].Tools = nil
+	config.Agents[0].GenerationParams = types.GenerationParams{Temperature: &zero, ResponseFormat: types.ResponseFormatJSON}
+	runner := NewRunner(config)
+
+	if _, err := runner.RunAgent(context.Background(), &config.Agents[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["temperature"] != 0.0 {
+		t.Errorf("expected the agent's temperature 0, got %v", body["temperature"])
+	}
+	if body["max_completion_tokens"] != 256.0 || body["seed"] != 7.0 {
+		t.Errorf("expected the model's max tokens and seed, got %v", body)
+	}
+	if format, _ := body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("expected JSON mode, got %v", body["response_format"])
+	}
+}
+
+func TestProviderParams(t *testing.T) {
+	temperature := 0.2
+	params := types.GenerationParams{Temperature: &temperature, MaxTokens: 100, Stop: []string{"END"}, ResponseFormat: types.ResponseFormatJSON}
+	messages := []types.ChatMessage{{Role: types.RoleUser, Content: "hi"}}
+
+	claude := (&ClaudeClient{Model: "claude"}).WithParams(params).(*ClaudeClient).payload(messages)
+	if claude["max_tokens"] != 100 || claude["temperature"] != 0.2 || claude["stop_sequences"] == nil {
+		t.Errorf("unexpected claude payload: %v", claude)
+	}
+	if defaults := (&ClaudeClient{}).payload(messages); defaults["max_tokens"] != claudeMaxTokens {
+		t.Errorf("expected claude's default max_tokens, got %v", defaults["max_tokens"])
+	}
+
+	gemini := geminiPayload(messages, params)["generationConfig"].(map[string]interface{})
+	if gemini["maxOutputTokens"] != 100 || gemini["responseMimeType"] != "application/json" {
+		t.Errorf("unexpected gemini generationConfig: %v", gemini)
+	}
+	if _, ok := geminiPayload(messages, types.GenerationParams{})["generationConfig"]; ok {
+		t.Error("expected no generationConfig without parameters")
+	}
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true}`))
+	}))
+	defer server.Close()
+	ollama := (&OllamaClient{Endpoint: server.URL, Model: "llama3"}).WithParams(params).(ChatClient)
+	if _, err := ollama.Chat(context.Background(), messages); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	options, _ := body["options"].(map[string]interface{})
+	if options["num_predict"] != 100.0 || options["temperature"] != 0.2 || body["format"] != "json" {
+		t.Errorf("unexpected ollama request: %v", body)
+	}
+}
+
+func TestProviderSchemaModes(t *testing.T) {
+	schema := map[string]interface{}{"type": "object", "additionalProperties": false}
+	params := types.GenerationParams{Schema: schema}
+	messages := []types.ChatMessage{{Role: types.RoleUser, Content: "hi"}}
+
+	gemini := geminiPayload(messages, params)["generationConfig"].(map[string]interface{})
+	if gemini["responseMimeType"] != "application/json" || gemini["responseSchema"] == nil {
+		t.Errorf("expected gemini's JSON mode with a schema, got %v", gemini)
+	}
+
+	payload := map[string]interface{}{}
+	setOpenAIParams(payload, params, "max_tokens")
+	if format := payload["response_format"].(map[string]string); format["type"] != "json_object" {
+		t.Errorf("expected JSON mode for compatible APIs, got %v", format)
+	}
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"{}"},"done":true}`))
+	}))
+	defer server.Close()
+	ollama := (&OllamaClient{Endpoint: server.URL, Model: "llama3"}).WithParams(params).(ChatClient)
+	if _, err := ollama.Chat(context.Background(), messages); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if format, _ := body["format"].(map[string]interface{}); format["type"] != "object" {
+		t.Errorf("expected the schema as ollama's format, got %v", body["format"])
+	}
+}
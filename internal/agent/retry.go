@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// Retry policy defaults, used for fields a policy leaves unset
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryDelay    = time.Second
+	DefaultMaxRetryDelay = 30 * time.Second
+	DefaultRetryBackoff  = 2.0
+	DefaultRetryJitter   = 0.2
+)
+
+// DefaultRetryOn lists the error kinds retried when a policy doesn't say
+var DefaultRetryOn = []types.ErrorKind{types.ErrorRateLimited, types.ErrorTransient}
+
+// retryPolicy decides whether and when a failed model call is retried
+type retryPolicy struct {
+	attempts   int
+	delay      time.Duration
+	maxDelay   time.Duration
+	multiplier float64
+	jitter     float64
+	retryOn    map[types.ErrorKind]bool
+}
+
+// newRetryPolicy combines retry configs, later ones overriding the fields
+// they set, on top of the defaults
+func newRetryPolicy(configs ...*types.RetryConfig) retryPolicy {
+	merged := types.RetryConfig{
+		MaxAttempts:  DefaultRetryAttempts,
+		InitialDelay: DefaultRetryDelay,
+		MaxDelay:     DefaultMaxRetryDelay,
+		Multiplier:   DefaultRetryBackoff,
+		Jitter:       DefaultRetryJitter,
+		RetryOn:      DefaultRetryOn,
+	}
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		if config.MaxAttempts > 0 {
+			merged.MaxAttempts = config.MaxAttempts
+		}
+		if config.InitialDelay > 0 {
+			merged.InitialDelay = config.InitialDelay
+		}
+		if config.MaxDelay > 0 {
+			merged.MaxDelay = config.MaxDelay
+		}
+		if config.Multiplier > 0 {
+			merged.Multiplier = config.Multiplier
+		}
+		if config.Jitter > 0 {
+			merged.Jitter = config.Jitter
+		}
+		if len(config.RetryOn) > 0 {
+			merged.RetryOn = config.RetryOn
+		}
+	}
+
+	policy := retryPolicy{
+		attempts:   merged.MaxAttempts,
+		delay:      merged.InitialDelay,
+		maxDelay:   merged.MaxDelay,
+		multiplier: merged.Multiplier,
+		jitter:     merged.Jitter,
+		retryOn:    make(map[types.ErrorKind]bool),
+	}
+	for _, kind := range merged.RetryOn {
+		policy.retryOn[kind] = true
+	}
+	return policy
+}
+
+// retryPolicyFor returns the retry policy of an agent's calls to a model:
+// the model's policy overridden by the agent's own
+func (r *Runner) retryPolicyFor(model string, agentDef *types.Agent) retryPolicy {
+	return newRetryPolicy(r.Config.Models[model].Retry, agentDef.Retry)
+}
+
+// retryable reports whether err is worth another attempt. Cancellation
+// never is.
+func (p retryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Dropped connections and broken streams
+		return p.retryOn[types.ErrorTransient]
+	}
+	return p.retryOn[apiErr.Kind]
+}
+
+// backoff returns how long to wait before the given retry (1 for the
+// first): an exponential delay with jitter, capped at the maximum, or the
+// wait the provider asked for when that is longer
+func (p retryPolicy) backoff(retry int, err error) time.Duration {
+	wait := float64(p.delay) * math.Pow(p.multiplier, float64(retry-1))
+	if wait > float64(p.maxDelay) {
+		wait = float64(p.maxDelay)
+	}
+	if p.jitter > 0 {
+		wait *= 1 + p.jitter*(2*rand.Float64()-1)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > time.Duration(wait) {
+		return apiErr.RetryAfter
+	}
+	return time.Duration(wait)
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+func TestNewAPIErrorClassifies(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   types.ErrorKind
+	}{
+		{429, `{"error":{"message":"Rate limit reached"}}`, types.ErrorRateLimited},
+		{429, `{"error":{"type":"insufficient_quota","message":"You exceeded your current quota"}}`, types.ErrorQuota},
+		{401, `{"error":{"message":"Incorrect API key"}}`, types.ErrorAuth},
+		{400, `{"error":{"message":"API key not valid. Please pass a valid API key."}}`, types.ErrorAuth},
+		{400, `{"error":{"message":"Invalid schema for function"}}`, types.ErrorBadRequest},
+		{529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, types.ErrorTransient},
+		{500, `{"error":"model crashed"}`, types.ErrorTransient},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+		err := newAPIError("test", resp)
+		if err.Kind != tt.want {
+			t.Errorf("%d %s: expected %s, got %s", tt.status, tt.body, tt.want, err.Kind)
+		}
+		if strings.Contains(err.Message, "{") {
+			t.Errorf("expected the message to be extracted from the body, got %q", err.Message)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter("7", ""); got != 7*time.Second {
+		t.Errorf("expected 7s from the header, got %v", got)
+	}
+	if got := retryAfter("", `{"details":[{"retryDelay": "12s"}]}`); got != 12*time.Second {
+		t.Errorf("expected 12s from Gemini's details, got %v", got)
+	}
+	if got := retryAfter("soon", ""); got != 0 {
+		t.Errorf("expected no wait for an unreadable header, got %v", got)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := newRetryPolicy(
+		&types.RetryConfig{MaxAttempts: 5, InitialDelay: time.Second, Multiplier: 3},
+		&types.RetryConfig{InitialDelay: 2 * time.Second, MaxDelay: 10 * time.Second, RetryOn: []types.ErrorKind{types.ErrorRateLimited}},
+	)
+	policy.jitter = 0
+	if policy.attempts != 5 {
+		t.Errorf("expected the model's attempts, got %d", policy.attempts)
+	}
+	if got := policy.backoff(2, errors.New("reset")); got != 6*time.Second {
+		t.Errorf("expected 2s*3 before the second retry, got %v", got)
+	}
+	if got := policy.backoff(4, errors.New("reset")); got != 10*time.Second {
+		t.Errorf("expected the backoff to be capped at 10s, got %v", got)
+	}
+	if got := policy.backoff(1, &APIError{Kind: types.ErrorRateLimited, RetryAfter: time.Minute}); got != time.Minute {
+		t.Errorf("expected Retry-After to win, got %v", got)
+	}
+
+	if !policy.retryable(&APIError{Kind: types.ErrorRateLimited}) {
+		t.Error("expected rate limits to be retried")
+	}
+	if policy.retryable(errors.New("connection reset")) {
+		t.Error("expected transient errors not to be retried when retry_on leaves them out")
+	}
+	if policy.retryable(context.Canceled) {
+		t.Error("cancellation should never be retried")
+	}
+}
+
+func TestRunAgentRetriesByErrorKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int
+		fails    bool
+	}{
+		{"transient", http.StatusServiceUnavailable, 2, false},
+		{"auth", http.StatusUnauthorized, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests == 1 {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(`{"error":{"message":"nope"}}`))
+					return
+				}
+				w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+			}))
+			defer server.Close()
+
+			config := toolTestConfig("openai", server.URL)
+			config.Agents[0].Tools = nil
+			config.Agents[0].Retry = &types.RetryConfig{InitialDelay: time.Millisecond}
+			runner := NewRunner(config)
+
+			_, err := runner.RunAgent(context.Background(), &config.Agents[0])
+			if (err != nil) != tt.fails {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if requests != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, requests)
+			}
+			if tt.fails && ErrorKindOf(err) != types.ErrorAuth {
+				t.Errorf("expected an auth error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxStreamLine bounds one line of a streamed response
+const maxStreamLine = 1024 * 1024
+
+// readSSE calls onData with the payload of each server-sent event until the
+// stream ends, a "[DONE]" payload arrives or onData fails
+func readSSE(r io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			return nil
+		}
+		if err := onData(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readNDJSON calls onLine with each non-empty line of a newline-delimited
+// JSON stream
+func readNDJSON(r io.Reader, onLine func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := onLine(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
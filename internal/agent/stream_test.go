@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+// sseServer replies to every request with the given events, checking that
+// streaming was requested
+func sseServer(t *testing.T, events ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true && r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("expected a streaming request, got %v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "%s\n\n", event)
+		}
+	}))
+}
+
+func collectStream(t *testing.T, client Streamer) (string, []string) {
+	reply, tokens := streamReply(t, client)
+	return reply.Content, tokens
+}
+
+func streamReply(t *testing.T, client Streamer) (types.ChatMessage, []string) {
+	var tokens []string
+	reply, err := client.GenerateStream(context.Background(), []types.ChatMessage{{Role: types.RoleUser, Content: "hi"}}, func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return reply, tokens
+}
+
+func TestOpenAIStream(t *testing.T) {
+	server := sseServer(t,
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}`,
+		`data: {"choices":[{"delta":{"content":"Hel"}}]}`,
+		`data: {"choices":[{"delta":{"content":"lo"}}]}`,
+		`data: [DONE]`,
+	)
+	defer server.Close()
+
+	text, tokens := collectStream(t, &OpenAIClient{Model: "test", Endpoint: server.URL})
+	if text != "Hello" || len(tokens) != 2 {
+		t.Errorf("expected 'Hello' in 2 tokens, got %q from %v", text, tokens)
+	}
+}
+
+func TestGenericStreamUsage(t *testing.T) {
+	var options interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		options = body["stream_options"]
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":3}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	reply, _ := streamReply(t, NewGenericClient("mistral", "test", "key", server.URL))
+	if options == nil || options.(map[string]interface{})["include_usage"] != true {
+		t.Errorf("expected include_usage to be requested, got %v", options)
+	}
+	if reply.Usage == nil || *reply.Usage != (types.Usage{InputTokens: 9, OutputTokens: 3}) {
+		t.Errorf("expected 9 input and 3 output tokens, got %+v", reply.Usage)
+	}
+
+	groq := sseServer(t,
+		`data: {"choices":[{"delta":{"content":"ok"}}]}`,
+		`data: {"choices":[{"delta":{}}],"x_groq":{"usage":{"prompt_tokens":4,"completion_tokens":2}}}`,
+		`data: [DONE]`,
+	)
+	defer groq.Close()
+	reply, _ = streamReply(t, NewGenericClient("groq", "test", "key", groq.URL))
+	if reply.Usage == nil || *reply.Usage != (types.Usage{InputTokens: 4, OutputTokens: 2}) {
+		t.Errorf("expected Groq's usage block to be read, got %+v", reply.Usage)
+	}
+}
+
+func TestClaudeStream(t *testing.T) {
+	server := sseServer(t,
+		"event: message_start\ndata: {\"type\":\"message_start\"}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi \"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"there\"}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	)
+	defer server.Close()
+
+	text, tokens := collectStream(t, &ClaudeClient{Model: "test", Endpoint: server.URL})
+	if text != "Hi there" || len(tokens) != 2 {
+		t.Errorf("expected 'Hi there' in 2 tokens, got %q from %v", text, tokens)
+	}
+}
+
+func TestClaudeStreamUsage(t *testing.T) {
+	server := sseServer(t,
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":7}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	)
+	defer server.Close()
+
+	reply, _ := streamReply(t, &ClaudeClient{Model: "test", Endpoint: server.URL})
+	if reply.Usage == nil || *reply.Usage != (types.Usage{InputTokens: 12, OutputTokens: 7}) {
+		t.Errorf("expected 12 input and 7 output tokens, got %+v", reply.Usage)
+	}
+}
+
+func TestClaudeStreamError(t *testing.T) {
+	server := sseServer(t, `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	defer server.Close()
+
+	client := &ClaudeClient{Model: "test", Endpoint: server.URL}
+	_, err := client.GenerateStream(context.Background(), []types.ChatMessage{{Role: types.RoleUser, Content: "hi"}}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") {
+		t.Errorf("expected the stream error, got %v", err)
+	}
+}
+
+func TestGeminiStream(t *testing.T) {
+	server := sseServer(t,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"One, "}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"two"}]}}]}`,
+	)
+	defer server.Close()
+
+	text, _ := collectStream(t, &GeminiClient{Model: "test", Endpoint: server.URL})
+	if text != "One, two" {
+		t.Errorf("expected 'One, two', got %q", text)
+	}
+}
+
+func TestGeminiStreamUsageCountsThoughts(t *testing.T) {
+	server := sseServer(t,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Done"}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":4,"thoughtsTokenCount":30}}`,
+	)
+	defer server.Close()
+
+	reply, _ := streamReply(t, &GeminiClient{Model: "test", Endpoint: server.URL})
+	if reply.Usage == nil || *reply.Usage != (types.Usage{InputTokens: 10, OutputTokens: 34}) {
+		t.Errorf("expected thinking tokens to count as output, got %+v", reply.Usage)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"a"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"b"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":20,"eval_count":2}`)
+	}))
+	defer server.Close()
+
+	reply, tokens := streamReply(t, &OllamaClient{Model: "test", Endpoint: server.URL})
+	if reply.Content != "ab" || len(tokens) != 2 {
+		t.Errorf("expected 'ab' in 2 tokens, got %q from %v", reply.Content, tokens)
+	}
+	if reply.Usage == nil || *reply.Usage != (types.Usage{InputTokens: 20, OutputTokens: 2}) {
+		t.Errorf("expected the final chunk's token counts, got %+v", reply.Usage)
+	}
+}
+
+func TestRunnerStreamsTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hello "},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"world"},"done":true}`)
+	}))
+	defer server.Close()
+
+	config := &types.WorkflowConfig{
+		Models: map[string]types.Model{"m": {Provider: "ollama", Model: "test", Endpoint: server.URL}},
+		Agents: []types.Agent{{ID: "writer", Goal: "Greet", Model: "m"}},
+	}
+	runner := NewRunner(config)
+
+	var tokens []string
+	runner.TokenCallback = func(agentID, token string) {
+		if agentID != "writer" {
+			t.Errorf("unexpected agent %q", agentID)
+		}
+		tokens = append(tokens, token)
+	}
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "Hello world" || runner.GetFinalOutput() != "Hello world" {
+		t.Errorf("expected the full text to be recorded, got %q", response)
+	}
+	if strings.Join(tokens, "|") != "Hello |world|" {
+		t.Errorf("expected two tokens and an end marker, got %q", tokens)
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"Orkflow/internal/schema"
+	"Orkflow/pkg/types"
+)
+
+// MaxSchemaCorrections is how many times a reply that doesn't match the
+// agent's output_schema is sent back for the model to correct
+const MaxSchemaCorrections = 2
+
+// schemaInstructions tells the model to reply with JSON matching schema
+func schemaInstructions(definition map[string]interface{}) string {
+	data, _ := json.MarshalIndent(definition, "", "  ")
+	return fmt.Sprintf("Respond only with JSON matching this schema, with no other text:\n%s", data)
+}
+
+// structureOutput decodes an agent's reply and checks it against the
+// agent's output schema. A reply that doesn't match goes back to the model
+// with the reason, up to MaxSchemaCorrections times. It returns the parsed
+// value and its JSON text.
+func (r *Runner) structureOutput(ctx context.Context, agentDef *types.Agent, conv *toolConversation, response string) (interface{}, string, error) {
+	validator, err := schema.Compile(agentDef.OutputSchema.Schema)
+	if err != nil {
+		return nil, "", fmt.Errorf("output_schema: %w", err)
+	}
+
+	for correction := 0; ; correction++ {
+		value, err := schema.Decode(response)
+		if err == nil {
+			err = validator.Validate(value)
+		}
+		if err == nil {
+			text, _ := json.Marshal(value)
+			return value, string(text), nil
+		}
+
+		if r.Logger != nil {
+			r.Logger.LogAgent(agentDef.ID, "SCHEMA_INVALID", err.Error())
+		}
+		if correction >= MaxSchemaCorrections {
+			return nil, "", fmt.Errorf("reply does not match output_schema after %d corrections: %w", correction, err)
+		}
+		fmt.Printf("[%s] ⚠️ Reply does not match output_schema (%v), asking for a correction...\n", agentDef.ID, err)
+
+		response, err = conv.answer(ctx, response, fmt.Sprintf(
+			"Your reply does not match the required JSON schema: %v\n\nReply again with only the corrected JSON.", err))
+		if err != nil {
+			r.endStream(agentDef.ID)
+			return nil, "", fmt.Errorf("schema correction failed: %w", err)
+		}
+		r.endStream(agentDef.ID)
+
+		// The corrected reply may call tools again
+		response, err = r.runToolLoop(ctx, agentDef, conv, response)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// memoryText renders a shared memory value for a prompt. Structured values
+// are written as JSON rather than Go's map syntax.
+func memoryText(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
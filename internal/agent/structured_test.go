@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+func TestRunAgentCorrectsStructuredOutput(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+
+		content := `{"verdict": "maybe"}`
+		if len(requests) > 1 {
+			content = "```json\n{\"verdict\": \"approve\", \"score\": 8}\n```"
+		}
+		reply, _ := json.Marshal(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": content}}},
+		})
+		w.Write(reply)
+	}))
+	defer server.Close()
+
+	config := toolTestConfig("openai", server.URL)
+	config.Agents[0].Tools = nil
+	config.Agents[0].Outputs = []string{"review"}
+	config.Agents[0].OutputSchema = &types.OutputSchema{Schema: map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"verdict", "score"},
+		"properties": map[string]interface{}{
+			"verdict": map[string]interface{}{"enum": []interface{}{"approve", "reject"}},
+			"score":   map[string]interface{}{"type": "integer"},
+		},
+	}}
+	runner := NewRunner(config)
+	runner.SharedMemory = memory.NewSharedMemory("test")
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected one correction, got %d requests", len(requests))
+	}
+	if response != `{"score":8,"verdict":"approve"}` {
+		t.Errorf("expected the normalized JSON, got %q", response)
+	}
+
+	format, _ := requests[0]["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" {
+		t.Errorf("expected OpenAI structured output, got %v", requests[0]["response_format"])
+	}
+	messages, _ := requests[1]["messages"].([]interface{})
+	last, _ := messages[len(messages)-1].(map[string]interface{})
+	if content, _ := last["content"].(string); !strings.Contains(content, "does not match") {
+		t.Errorf("expected the correction request last, got %v", last)
+	}
+
+	value, _ := runner.SharedMemory.Get("review")
+	if review, ok := value.(map[string]interface{}); !ok || review["verdict"] != "approve" {
+		t.Errorf("expected the parsed object in shared memory, got %#v", value)
+	}
+}
+
+func TestRunAgentFailsOnPersistentSchemaMismatch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"not json"}}]}`))
+	}))
+	defer server.Close()
+
+	config := toolTestConfig("openai", server.URL)
+	config.Agents[0].Tools = nil
+	config.Agents[0].OutputSchema = &types.OutputSchema{Schema: map[string]interface{}{"type": "object"}}
+	runner := NewRunner(config)
+
+	_, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err == nil || !strings.Contains(err.Error(), "output_schema") {
+		t.Fatalf("expected an output_schema error, got %v", err)
+	}
+	if requests != 1+MaxSchemaCorrections {
+		t.Errorf("expected %d requests, got %d", 1+MaxSchemaCorrections, requests)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	runner := NewRunner(&types.WorkflowConfig{})
+	runner.SharedMemory = memory.NewSharedMemory("test")
+	runner.SharedMemory.Set("review", map[string]interface{}{"score": 8, "issues": []interface{}{"typo"}})
+	runner.Context.AddOutput("writer", "a draft")
+
+	got, err := runner.renderTemplate("Score {{ memory.review.score }}, issues {{ memory.review.issues }}, draft: {{outputs.writer}}, {{ .Name }}")
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	want := `Score 8, issues ["typo"], draft: a draft, {{ .Name }}`
+	if got != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+
+	// Fields of missing keys render as nothing
+	got, err = runner.renderTemplate("[{{ memory.missing.field }}][{{ memory.review.notes.first }}]")
+	if err != nil || got != "[][]" {
+		t.Errorf("expected missing values to render empty, got %q, %v", got, err)
+	}
+}
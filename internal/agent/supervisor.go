@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+const (
+	DefaultMaxDelegations = 10 // Sub-agent calls a supervisor may make per run
+	DefaultMaxDepth       = 3  // Levels of nested supervisors, counting the root
+)
+
+// delegationResult is a finished sub-agent call fed back to the supervisor
+type delegationResult struct {
+	Delegation
+	Result string
+}
+
+// RunSupervisor runs a supervisor agent. Each turn the supervisor either
+// delegates tasks to its sub-agents with <delegate to="id">task</delegate>
+// directives, whose results are fed back on the next turn, or replies
+// without directives, which is taken as its final answer.
+func (r *Runner) RunSupervisor(ctx context.Context, agentDef *types.Agent) (string, error) {
+	maxDepth := agentDef.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	return r.runSupervisor(ctx, agentDef, 1, maxDepth)
+}
+
+func (r *Runner) runSupervisor(ctx context.Context, agentDef *types.Agent, depth, maxDepth int) (string, error) {
+	maxDelegations := agentDef.MaxDelegations
+	if maxDelegations <= 0 {
+		maxDelegations = DefaultMaxDelegations
+	}
+
+	fmt.Printf("[%s] 👔 Supervising %v (depth %d/%d, max %d delegations)\n",
+		agentDef.ID, agentDef.SubAgents, depth, maxDepth, maxDelegations)
+	if r.Logger != nil {
+		r.Logger.LogAgent(agentDef.ID, "SUPERVISOR_START", fmt.Sprintf("SubAgents: %v, Depth: %d", agentDef.SubAgents, depth))
+	}
+
+	// Turns aren't recorded; only the final answer is published, added to
+	// the context history and saved to the session
+	r.startStats(agentDef)
+	startTime := time.Now()
+	finish := func(answer, model string) string {
+		r.publishOutputs(agentDef, answer)
+		r.recordOutput(agentDef, answer, model, 0, time.Since(startTime))
+		return answer
+	}
+
+	var results []delegationResult
+	used := 0
+
+	for {
+		remaining := maxDelegations - used
+		turn := agentDef.WithInstruction(r.supervisorInstruction(agentDef, results, remaining))
+		response, model, err := r.runAgent(ctx, turn, 0, false)
+		if err != nil {
+			return "", err
+		}
+
+		delegations := ParseDelegations(response)
+		if len(delegations) == 0 {
+			return finish(response, model), nil
+		}
+		if remaining <= 0 {
+			// The supervisor was told it had no delegations left
+			fmt.Printf("[%s] ⚠️  Delegation limit reached, using reply as final answer\n", agentDef.ID)
+			if r.Logger != nil {
+				r.Logger.LogAgent(agentDef.ID, "DELEGATION_LIMIT", fmt.Sprintf("%d", maxDelegations))
+			}
+			return finish(StripDelegations(response), model), nil
+		}
+
+		for _, d := range delegations {
+			if used >= maxDelegations {
+				results = append(results, delegationResult{d, "ERROR: delegation limit reached, task not run"})
+				continue
+			}
+			used++
+
+			result, err := r.delegate(ctx, agentDef, d, depth, maxDepth)
+			if err != nil {
+				return "", err
+			}
+			results = append(results, delegationResult{d, result})
+		}
+	}
+}
+
+// delegate runs a single delegated task on a sub-agent. Sub-agents that are
+// supervisors themselves keep delegating until maxDepth is reached.
+func (r *Runner) delegate(ctx context.Context, supervisor *types.Agent, d Delegation, depth, maxDepth int) (string, error) {
+	if !isSubAgent(supervisor, d.To) {
+		return fmt.Sprintf("ERROR: %s is not one of your sub-agents", d.To), nil
+	}
+	sub := r.GetAgent(d.To)
+	if sub == nil {
+		return "", fmt.Errorf("sub-agent not found: %s", d.To)
+	}
+
+	fmt.Printf("[%s] ➡️  Delegating to %s: %s\n", supervisor.ID, d.To, truncate(d.Task, 60))
+	if r.Logger != nil {
+		r.Logger.LogAgent(supervisor.ID, "DELEGATE", fmt.Sprintf("To: %s, Task: %s", d.To, d.Task))
+	}
+
+	task := sub.WithInstruction("## Task from " + supervisor.ID + "\n\n" + d.Task)
+	if sub.IsSupervisor() && depth < maxDepth {
+		return r.runSupervisor(ctx, task, depth+1, maxDepth)
+	}
+	if sub.IsSupervisor() {
+		fmt.Printf("[%s] ⚠️  Max depth %d reached, running without delegation\n", sub.ID, maxDepth)
+	}
+	return r.RunAgent(ctx, task)
+}
+
+// supervisorInstruction lists the sub-agents, explains the delegation
+// directive and feeds back the results of earlier delegations.
+func (r *Runner) supervisorInstruction(agentDef *types.Agent, results []delegationResult, remaining int) string {
+	var sb strings.Builder
+	sb.WriteString("## Sub-Agents\n\nYou can delegate tasks to these agents:\n")
+	for _, id := range agentDef.SubAgents {
+		sub := r.GetAgent(id)
+		if sub == nil {
+			continue
+		}
+		description := sub.Description
+		if description == "" {
+			description = sub.Goal
+		}
+		sb.WriteString(fmt.Sprintf("- %s", id))
+		if sub.Role != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", sub.Role))
+		}
+		if description != "" {
+			sb.WriteString(": " + strings.TrimSpace(description))
+		}
+		sb.WriteString("\n")
+	}
+
+	if remaining > 0 {
+		sb.WriteString(fmt.Sprintf(`
+To delegate, reply with one or more directives:
+<delegate to="agent_id">task description</delegate>
+Each result will be returned to you. Delegations remaining: %d.
+When you have everything you need, reply with your final answer and no delegate tags.
+`, remaining))
+	} else {
+		sb.WriteString("\nNo delegations remaining. Reply with your final answer now.\n")
+	}
+
+	if len(results) > 0 {
+		sb.WriteString("\n## Delegation Results\n")
+		for i, res := range results {
+			sb.WriteString(fmt.Sprintf("\n### %d. %s: %s\n%s\n", i+1, res.To, res.Task, res.Result))
+		}
+	}
+	return sb.String()
+}
+
+// publishOutputs stores a supervisor's final answer under its output keys
+func (r *Runner) publishOutputs(agentDef *types.Agent, response string) {
+	if r.SharedMemory == nil {
+		return
+	}
+	for _, key := range agentDef.Outputs {
+		r.SharedMemory.Set(key, response)
+		fmt.Printf("[%s] 📤 Published '%s' to shared memory\n", agentDef.ID, key)
+		if r.Logger != nil {
+			r.Logger.LogAgent(agentDef.ID, "SHARED_MEMORY_PUBLISH", key)
+		}
+	}
+}
+
+func isSubAgent(supervisor *types.Agent, id string) bool {
+	for _, sub := range supervisor.SubAgents {
+		if sub == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"Orkflow/pkg/types"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+)
+
+// templatePattern matches a {{ expression }} in an agent's prompt
+var templatePattern = regexp.MustCompile(`(?s)\{\{(.+?)\}\}`)
+
+// templateEnv builds the variables visible to prompt templates, the same
+// ones when/until conditions see:
+//
+//	memory  - shared memory keys, e.g. {{ memory.review.score }}
+//	outputs - latest response per agent, e.g. {{ outputs.researcher }}
+func (r *Runner) templateEnv() map[string]interface{} {
+	env := map[string]interface{}{
+		"memory":  map[string]interface{}{},
+		"outputs": r.Context.Outputs(),
+	}
+	if r.SharedMemory != nil {
+		env["memory"] = r.SharedMemory.Snapshot()
+	}
+	return env
+}
+
+// renderTemplate replaces each {{ expression }} in text with its value.
+// Strings are inserted as they are, missing values as nothing and other
+// values as JSON. Braces that don't hold a valid expression are left alone.
+func (r *Runner) renderTemplate(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	env := r.templateEnv()
+	var renderErr error
+	rendered := templatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		expression := strings.TrimSpace(templatePattern.FindStringSubmatch(match)[1])
+		if renderErr != nil {
+			return match
+		}
+		program, err := expr.Compile(expression, expr.Env(env), expr.Patch(nilSafe{}))
+		if err != nil {
+			return match
+		}
+		value, err := expr.Run(program, env)
+		if err != nil {
+			renderErr = fmt.Errorf("template {{ %s }}: %w", expression, err)
+			return match
+		}
+		if value == nil {
+			return ""
+		}
+		return memoryText(value)
+	})
+	return rendered, renderErr
+}
+
+// nilSafe turns every member access into "?.", so fields of a missing key
+// render as nothing instead of failing the agent
+type nilSafe struct{}
+
+func (nilSafe) Visit(node *ast.Node) {
+	if member, ok := (*node).(*ast.MemberNode); ok && !member.Method {
+		member.Optional = true
+		ast.Patch(node, &ast.ChainNode{Node: member})
+	}
+}
+
+// renderPrompt returns a copy of the agent with the templates in its goal
+// and instruction filled in from the current run. Text added with
+// WithInstruction, such as foreach items and delegated tasks, comes from
+// models and users and is not rendered.
+func (r *Runner) renderPrompt(agentDef *types.Agent) (*types.Agent, error) {
+	goal, err := r.renderTemplate(agentDef.Goal)
+	if err != nil {
+		return nil, err
+	}
+	instruction, err := r.renderTemplate(agentDef.Instruction)
+	if err != nil {
+		return nil, err
+	}
+	if goal == agentDef.Goal && instruction == agentDef.Instruction {
+		return agentDef, nil
+	}
+	clone := *agentDef
+	clone.Goal, clone.Instruction = goal, instruction
+	return &clone, nil
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"Orkflow/internal/tools"
+	"Orkflow/pkg/types"
+)
+
+// ToolSpec describes a tool offered to the model
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // JSON Schema of the arguments
+}
+
+// agentTools returns the tools an agent may call from the run's registry:
+// its listed tools followed by the tools of its MCP toolsets
+func (r *Runner) agentTools(agentDef *types.Agent) []tools.Tool {
+	var allTools []tools.Tool
+
+	// 1. Add explicitly listed tools
+	if len(agentDef.Tools) > 0 {
+		listed, err := r.Tools.GetByNames(agentDef.Tools)
+		if err == nil {
+			allTools = append(allTools, listed...)
+		}
+	}
+
+	// 2. Add tools from toolsets (MCP servers)
+	for _, toolset := range agentDef.Toolsets {
+		// Get tools starting with "serverName."
+		allTools = append(allTools, r.Tools.GetByPrefix(toolset+".")...)
+	}
+
+	return allTools
+}
+
+// toolsFor returns an agent's tools, with the file tool confined by the
+// workflow's file_access policy when it has one. Sandboxed tools report
+// refusals as the agent's. Scripts get the run's shared memory and may
+// call the agent's other tools.
+func (r *Runner) toolsFor(agentDef *types.Agent) []tools.Tool {
+	list := r.agentTools(agentDef)
+	script := -1
+	for i, tool := range list {
+		switch tool := tool.(type) {
+		case *tools.FileTool:
+			if r.filePolicy != nil {
+				list[i] = &tools.FileTool{Policy: r.filePolicy, OnDeny: r.denyHandler(agentDef.ID, tool.Name())}
+			}
+		case *tools.ShellTool:
+			shell := *tool
+			shell.OnDeny = r.denyHandler(agentDef.ID, tool.Name())
+			list[i] = &shell
+		case *tools.HTTPTool:
+			http := *tool
+			http.OnDeny = r.denyHandler(agentDef.ID, tool.Name())
+			list[i] = &http
+		case *tools.ScriptTool:
+			script = i
+		}
+	}
+	if script >= 0 {
+		others := make([]tools.Tool, 0, len(list)-1)
+		others = append(others, list[:script]...)
+		others = append(others, list[script+1:]...)
+		tool := *list[script].(*tools.ScriptTool)
+		tool.Memory = r.SharedMemory
+		tool.Tools = tools.NewRegistry(others...)
+		list[script] = &tool
+	}
+	return list
+}
+
+// denyHandler reports operations of a sandboxed tool an agent was refused
+func (r *Runner) denyHandler(agentID, toolName string) func(input, reason string) {
+	return func(input, reason string) {
+		fmt.Printf("[%s] 🚫 %s\n", agentID, reason)
+		if r.Logger != nil {
+			r.Logger.LogToolCall(toolName, input, "DENIED: "+reason)
+		}
+	}
+}
+
+// toolSpecs describes tools for a native API. Providers only accept letters,
+// digits, '_' and '-' in names, so MCP names like "server.tool" are sent as
+// "server__tool"; the returned map translates them back.
+func toolSpecs(list []tools.Tool) ([]ToolSpec, map[string]string) {
+	specs := make([]ToolSpec, 0, len(list))
+	names := make(map[string]string, len(list))
+	for _, tool := range list {
+		name := strings.ReplaceAll(tool.Name(), ".", "__")
+		names[name] = tool.Name()
+		specs = append(specs, ToolSpec{
+			Name:        name,
+			Description: tool.Description(),
+			Parameters:  tool.InputSchema(),
+		})
+	}
+	return specs, names
+}
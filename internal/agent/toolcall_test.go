@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"Orkflow/internal/memory"
+	"Orkflow/internal/tools"
+	"Orkflow/pkg/types"
+)
+
+func toolTestConfig(provider, endpoint string) *types.WorkflowConfig {
+	return &types.WorkflowConfig{
+		Models: map[string]types.Model{
+			"m": {Provider: provider, Model: "test", APIKey: "key", Endpoint: endpoint},
+		},
+		Agents: []types.Agent{
+			{ID: "analyst", Role: "Analyst", Goal: "Compute 6*7", Model: "m", Tools: []string{"calc"}},
+		},
+	}
+}
+
+func TestOpenAINativeToolCalls(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+
+		if len(requests) == 1 {
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+				{"id":"call_1","type":"function","function":{"name":"calc","arguments":"{\"expression\":\"6*7\"}"}}]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"The answer is 42"}}]}`))
+	}))
+	defer server.Close()
+
+	config := toolTestConfig("openai", server.URL)
+	runner := NewRunner(config)
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "The answer is 42" {
+		t.Errorf("expected the follow-up reply, got %q", response)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	tools, _ := requests[0]["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("expected calc to be offered as a function, got %v", requests[0]["tools"])
+	}
+	prompt := requests[0]["messages"].([]interface{})[0].(map[string]interface{})["content"].(string)
+	if strings.Contains(prompt, "```tool:") {
+		t.Error("native requests should not describe the fence format")
+	}
+
+	messages := requests[1]["messages"].([]interface{})
+	last := messages[len(messages)-1].(map[string]interface{})
+	if last["role"] != "tool" || last["tool_call_id"] != "call_1" || last["content"] != "42" {
+		t.Errorf("expected the calc result as a tool message, got %v", last)
+	}
+}
+
+// fenceClient has no native tool support
+type fenceClient struct {
+	prompts []string
+}
+
+func (f *fenceClient) Generate(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if len(f.prompts) == 1 {
+		return "```tool:calc\n6*7\n```", nil
+	}
+	return "The answer is 42", nil
+}
+
+func TestFenceToolCallsFallback(t *testing.T) {
+	config := toolTestConfig("ollama", "")
+	runner := NewRunner(config)
+	client := &fenceClient{}
+	runner.Clients["m"] = client
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "The answer is 42" {
+		t.Errorf("expected the follow-up reply, got %q", response)
+	}
+	if !strings.Contains(client.prompts[0], "```tool:<tool_name>") {
+		t.Error("expected the fence format to be described in the prompt")
+	}
+	if !strings.Contains(client.prompts[1], "[calc]:\n42") {
+		t.Errorf("expected the tool result in the follow-up prompt, got %q", client.prompts[1])
+	}
+}
+
+func TestClaudeMessagesMergeToolResults(t *testing.T) {
+	system, messages := toClaudeMessages([]types.ChatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "a", Name: "calc"}, {ID: "b", Name: "calc"}}},
+		{Role: "tool", ToolCallID: "a", Content: "1"},
+		{Role: "tool", ToolCallID: "b", Content: "2"},
+	})
+
+	if system != "be brief" {
+		t.Errorf("expected the system prompt to move out, got %q", system)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	results := messages[2]
+	if results.Role != "user" || len(results.Content) != 2 || results.Content[1].ToolUseID != "b" {
+		t.Errorf("expected both tool results in one user message, got %+v", results)
+	}
+	if input, ok := messages[1].Content[0].Input.(map[string]interface{}); !ok || input == nil {
+		t.Error("tool_use blocks need an input object even without arguments")
+	}
+}
+
+func TestGeminiSchemaDropsUnsupportedKeys(t *testing.T) {
+	schema := geminiSchema(map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{"type": "string", "additionalProperties": false},
+		},
+	})
+
+	if _, ok := schema["$schema"]; ok {
+		t.Error("$schema should be dropped")
+	}
+	if _, ok := schema["additionalProperties"]; ok {
+		t.Error("additionalProperties should be dropped")
+	}
+	path := schema["properties"].(map[string]interface{})["path"].(map[string]interface{})
+	if _, ok := path["additionalProperties"]; ok || path["type"] != "string" {
+		t.Errorf("nested schemas should be cleaned too, got %v", path)
+	}
+}
+
+func TestToolsForConfinesSandboxedTools(t *testing.T) {
+	config := toolTestConfig("openai", "")
+	config.Agents[0].Tools = []string{"calc", "file", "shell"}
+	config.FileAccess = &types.FileAccessConfig{Roots: []string{t.TempDir()}}
+	config.Shell = &types.ShellConfig{Allow: []string{"go test"}}
+	runner := NewRunner(config)
+
+	list := runner.toolsFor(&config.Agents[0])
+	file, ok := list[1].(*tools.FileTool)
+	if !ok || file.Policy == nil || file.OnDeny == nil {
+		t.Fatalf("expected a confined file tool, got %#v", list[1])
+	}
+	if shell, ok := list[2].(*tools.ShellTool); !ok || shell.Policy == nil || shell.OnDeny == nil {
+		t.Errorf("expected the workflow's confined shell tool, got %#v", list[2])
+	}
+	if registered, _ := tools.Get("file"); registered.(*tools.FileTool).Policy != nil {
+		t.Error("the registered file tool should be left unconfined")
+	}
+}
+
+func TestToolsForGivesScriptsMemoryAndTools(t *testing.T) {
+	config := toolTestConfig("openai", "")
+	config.Agents[0].Tools = []string{"calc", "script"}
+	config.Script = &types.ScriptConfig{MaxSteps: 50}
+	runner := NewRunner(config)
+	runner.SharedMemory = memory.NewSharedMemory("test")
+
+	list := runner.toolsFor(&config.Agents[0])
+	script, ok := list[1].(*tools.ScriptTool)
+	if !ok || script.Memory != runner.SharedMemory || script.MaxSteps != 50 {
+		t.Fatalf("expected the workflow's script tool with shared memory, got %#v", list[1])
+	}
+	if names := script.Tools.ListNames(); len(names) != 1 || names[0] != "calc" {
+		t.Errorf("expected scripts to call only the agent's other tools, got %v", names)
+	}
+}
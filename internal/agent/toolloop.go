@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Orkflow/internal/tools"
+	"Orkflow/pkg/types"
+)
+
+const DefaultMaxToolIterations = 10
+
+// toolConversation tracks an agent's exchange with the model while it calls
+// tools, either through the provider's native API or through ```tool: fences
+type toolConversation struct {
+	client   LLMClient
+	model    string     // Configured name of the model serving the conversation
+	caller   ToolCaller // Set when tools are called natively
+	tools    []tools.Tool
+	allowed  *tools.Registry // The agent's tools; the only ones its calls may run
+	specs    []ToolSpec
+	names    map[string]string
+	followup string                                                        // Instruction closing each fence follow-up prompt
+	onToken  func(token string)                                            // Receives streamed text when set
+	onCall   func(model string, usage types.Usage, duration time.Duration) // Receives each model call's usage when set
+	guard    func() error                                                  // Checked before each model call when set
+	retry    retryPolicy
+	onRetry  func(attempt int, err error, wait time.Duration) // Told about each failed attempt that is retried
+	fallback func(err error) bool                             // Switches to a fallback model after a failure, reporting whether it did
+
+	messages []types.ChatMessage // Conversation so far
+	reply    types.ChatMessage   // Last native reply
+}
+
+// newToolConversation prepares an agent's tools for the client. Native
+// calling is used when the client supports it and the agent has tools.
+func newToolConversation(client LLMClient, agentTools []tools.Tool) *toolConversation {
+	conv := &toolConversation{
+		tools:    agentTools,
+		allowed:  tools.NewRegistry(agentTools...),
+		followup: "Now provide your final response incorporating the tool results:",
+		retry:    newRetryPolicy(),
+	}
+	conv.setClient(client)
+	return conv
+}
+
+// setClient makes client serve the conversation from now on
+func (c *toolConversation) setClient(client LLMClient) {
+	c.client = client
+	c.caller, c.specs, c.names = nil, nil, nil
+	if caller, ok := client.(ToolCaller); ok && len(c.tools) > 0 {
+		c.caller = caller
+		c.specs, c.names = toolSpecs(c.tools)
+	}
+}
+
+// switchModel moves the conversation to another model. When the new client
+// calls tools differently, the fence-format tool descriptions are added to
+// or removed from the system message.
+func (c *toolConversation) switchModel(name string, client LLMClient, retry retryPolicy) {
+	oldDocs := c.describeTools()
+	c.model = name
+	c.retry = retry
+	c.setClient(client)
+
+	newDocs := c.describeTools()
+	if oldDocs == newDocs || len(c.messages) == 0 || c.messages[0].Role != types.RoleSystem {
+		return
+	}
+	system := c.messages[0].Content
+	if oldDocs != "" {
+		if !strings.HasSuffix(system, oldDocs) {
+			return
+		}
+		system = strings.TrimSuffix(strings.TrimSuffix(system, oldDocs), "\n\n")
+	}
+	if newDocs != "" {
+		system = strings.TrimPrefix(system+"\n\n"+newDocs, "\n\n")
+	}
+	c.messages[0].Content = system
+}
+
+// describeTools returns the fence-format tool descriptions for the prompt,
+// or "" when tools are called natively or the agent has none
+func (c *toolConversation) describeTools() string {
+	if c.caller != nil || len(c.tools) == 0 {
+		return ""
+	}
+	return tools.FormatToolsForPrompt(c.tools)
+}
+
+// streams reports whether text responses are streamed. Native tool calls
+// are not streamed.
+func (c *toolConversation) streams() bool {
+	if c.onToken == nil || c.caller != nil {
+		return false
+	}
+	_, ok := c.client.(Streamer)
+	return ok
+}
+
+// generate starts the conversation with the given messages
+func (c *toolConversation) generate(ctx context.Context, messages []types.ChatMessage) (string, error) {
+	c.messages = append([]types.ChatMessage{}, messages...)
+	return c.send(ctx)
+}
+
+// send asks the model for the next reply to the conversation, streaming it
+// when possible, and reports the call's usage. Failed calls are retried as
+// the retry policy allows, then handed to the fallback models.
+func (c *toolConversation) send(ctx context.Context) (string, error) {
+	for {
+		reply, err := c.attempt(ctx)
+		if err == nil {
+			if c.caller != nil {
+				c.reply = reply
+			}
+			return reply.Content, nil
+		}
+		var refused *guardError
+		if errors.As(err, &refused) {
+			return "", refused.err
+		}
+		if c.fallback == nil || ctx.Err() != nil || !c.fallback(err) {
+			return "", err
+		}
+	}
+}
+
+// guardError is a call refused by the guard, which no fallback can help
+type guardError struct {
+	err error
+}
+
+func (e *guardError) Error() string { return e.err.Error() }
+func (e *guardError) Unwrap() error { return e.err }
+
+// attempt makes one model call, retrying it as the retry policy allows
+func (c *toolConversation) attempt(ctx context.Context) (types.ChatMessage, error) {
+	for attempt := 1; ; attempt++ {
+		if c.guard != nil {
+			if err := c.guard(); err != nil {
+				return types.ChatMessage{}, &guardError{err: err}
+			}
+		}
+		start := time.Now()
+		reply, err := c.request(ctx)
+		if err == nil {
+			if c.onCall != nil {
+				var usage types.Usage
+				if reply.Usage != nil {
+					usage = *reply.Usage
+				}
+				c.onCall(c.model, usage, time.Since(start))
+			}
+			return reply, nil
+		}
+
+		if attempt >= c.retry.attempts || !c.retry.retryable(err) {
+			if attempt > 1 {
+				return types.ChatMessage{}, fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+			return types.ChatMessage{}, err
+		}
+		wait := c.retry.backoff(attempt, err)
+		if c.onRetry != nil {
+			c.onRetry(attempt, err, wait)
+		}
+		if sleepContext(ctx, wait) != nil {
+			return types.ChatMessage{}, err
+		}
+	}
+}
+
+// request makes one model call through the richest API the client has
+func (c *toolConversation) request(ctx context.Context) (types.ChatMessage, error) {
+	if c.caller != nil {
+		return c.caller.GenerateWithTools(ctx, c.messages, c.specs)
+	}
+	if c.streams() {
+		return c.client.(Streamer).GenerateStream(ctx, c.messages, c.onToken)
+	}
+	if chat, ok := c.client.(ChatClient); ok {
+		return chat.Chat(ctx, c.messages)
+	}
+	text, err := c.client.Generate(ctx, flattenMessages(c.messages))
+	return types.ChatMessage{Role: types.RoleAssistant, Content: text}, err
+}
+
+// pendingCalls returns the tool calls the model asked for in its last response
+func (c *toolConversation) pendingCalls(response string) []tools.ToolCall {
+	if c.caller == nil {
+		if len(c.tools) == 0 || !tools.HasToolCalls(response) {
+			return nil
+		}
+		return tools.ParseToolCalls(response)
+	}
+
+	calls := make([]tools.ToolCall, 0, len(c.reply.ToolCalls))
+	for _, call := range c.reply.ToolCalls {
+		name, ok := c.names[call.Name]
+		if !ok {
+			name = call.Name
+		}
+		args := call.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		input, _ := json.Marshal(args)
+		calls = append(calls, tools.ToolCall{Name: name, Input: string(input), Args: args})
+	}
+	return calls
+}
+
+// continueWith sends tool results back to the model and returns its next
+// response. Native results go back as tool messages; fence results as a
+// user message after the response that asked for them.
+func (c *toolConversation) continueWith(ctx context.Context, response string, results []tools.ToolResult) (string, error) {
+	if c.caller == nil {
+		c.messages = append(c.messages,
+			types.ChatMessage{Role: types.RoleAssistant, Content: response},
+			types.ChatMessage{Role: types.RoleUser, Content: strings.TrimSpace(tools.FormatToolResults(results)) + "\n\n" + c.followup},
+		)
+		return c.send(ctx)
+	}
+
+	c.messages = append(c.messages, c.reply)
+	for i, res := range results {
+		c.messages = append(c.messages, types.ChatMessage{
+			Role:       types.RoleTool,
+			Content:    toolResultText(res),
+			ToolCallID: c.reply.ToolCalls[i].ID,
+			Name:       c.reply.ToolCalls[i].Name,
+		})
+	}
+	return c.send(ctx)
+}
+
+// answer replies to the model's last response with a user message and returns
+// its next response
+func (c *toolConversation) answer(ctx context.Context, response, message string) (string, error) {
+	c.messages = append(c.messages,
+		types.ChatMessage{Role: types.RoleAssistant, Content: response},
+		types.ChatMessage{Role: types.RoleUser, Content: message},
+	)
+	return c.send(ctx)
+}
+
+// runToolLoop executes the tool calls in response and feeds the results back
+// until the model answers without calling a tool. It fails when the model is
+// still calling tools after the agent's max_tool_iterations rounds.
+func (r *Runner) runToolLoop(ctx context.Context, agentDef *types.Agent, conv *toolConversation, response string) (string, error) {
+	maxIterations := agentDef.MaxToolIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxToolIterations
+	}
+
+	for iteration := 1; ; iteration++ {
+		calls := conv.pendingCalls(response)
+		if len(calls) == 0 {
+			return response, nil
+		}
+		if iteration > maxIterations {
+			if r.Logger != nil {
+				r.Logger.LogAgent(agentDef.ID, "TOOL_LIMIT_REACHED", fmt.Sprintf("Iterations: %d", maxIterations))
+			}
+			return "", fmt.Errorf("still calling tools after %d iterations (max_tool_iterations)", maxIterations)
+		}
+
+		r.endStream(agentDef.ID)
+		fmt.Printf("[%s] 🛠️ Tool iteration %d/%d: executing %d tool calls...\n", agentDef.ID, iteration, maxIterations, len(calls))
+		if r.Logger != nil {
+			r.Logger.LogAgent(agentDef.ID, "TOOL_ITERATION", fmt.Sprintf("Iteration: %d, Calls: %d", iteration, len(calls)))
+		}
+
+		results := conv.allowed.ExecuteToolCalls(ctx, calls)
+		if r.Logger != nil {
+			for i, res := range results {
+				r.Logger.LogToolCall(res.ToolName, calls[i].Input, toolResultText(res))
+			}
+		}
+
+		next, err := conv.continueWith(ctx, response, results)
+		if err != nil {
+			r.endStream(agentDef.ID)
+			return "", fmt.Errorf("tool follow-up %d failed: %w", iteration, err)
+		}
+		response = next
+		r.endStream(agentDef.ID)
+		fmt.Printf("[%s] ✓ Follow-up completed (%d chars)\n", agentDef.ID, len(response))
+	}
+}
+
+// toolResultText is the text the model sees for a tool result
+func toolResultText(res tools.ToolResult) string {
+	if res.Error != nil {
+		return fmt.Sprintf("ERROR: %v", res.Error)
+	}
+	return res.Output
+}
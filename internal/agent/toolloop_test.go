@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+// scriptedClient replies with its responses in order, failing once they run out
+type scriptedClient struct {
+	responses []string
+	prompts   []string
+}
+
+func (s *scriptedClient) Generate(ctx context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	if len(s.prompts) > len(s.responses) {
+		return "", errors.New("no more responses")
+	}
+	return s.responses[len(s.prompts)-1], nil
+}
+
+func TestToolLoopRunsUntilNoCalls(t *testing.T) {
+	config := toolTestConfig("ollama", "")
+	runner := NewRunner(config)
+	client := &scriptedClient{responses: []string{
+		"```tool:calc\n6*7\n```",
+		"```tool:calc\n42+1\n```",
+		"The answer is 43",
+	}}
+	runner.Clients["m"] = client
+
+	response, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response != "The answer is 43" {
+		t.Errorf("expected the final reply, got %q", response)
+	}
+	if len(client.prompts) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(client.prompts))
+	}
+	if !strings.Contains(client.prompts[2], "[calc]:\n42\n") || !strings.Contains(client.prompts[2], "[calc]:\n43\n") {
+		t.Errorf("expected both rounds of results in the last prompt, got %q", client.prompts[2])
+	}
+}
+
+func TestToolLoopMaxIterations(t *testing.T) {
+	config := toolTestConfig("ollama", "")
+	config.Agents[0].MaxToolIterations = 2
+	runner := NewRunner(config)
+	call := "```tool:calc\n1+1\n```"
+	runner.Clients["m"] = &scriptedClient{responses: []string{call, call, call, call}}
+
+	_, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err == nil || !strings.Contains(err.Error(), "max_tool_iterations") {
+		t.Errorf("expected a max_tool_iterations error, got %v", err)
+	}
+}
+
+func TestToolLoopFollowupError(t *testing.T) {
+	config := toolTestConfig("ollama", "")
+	runner := NewRunner(config)
+	runner.Clients["m"] = &scriptedClient{responses: []string{"```tool:calc\n1+1\n```"}}
+
+	_, err := runner.RunAgent(context.Background(), &config.Agents[0])
+	if err == nil || !strings.Contains(err.Error(), "no more responses") {
+		t.Errorf("expected the follow-up error to be returned, got %v", err)
+	}
+}
+
+func TestCollaborativeAgentUsesToolLoop(t *testing.T) {
+	config := toolTestConfig("ollama", "")
+	config.Agents[0].MaxTurns = 1
+	runner := NewRunner(config)
+	client := &scriptedClient{responses: []string{
+		"```tool:calc\n6*7\n```",
+		"The answer is 42 <DONE/>",
+	}}
+	runner.Clients["m"] = client
+
+	channel := memory.NewMessageChannel(0)
+	output, err := runner.RunCollaborativeAgent(context.Background(), &config.Agents[0], channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "The answer is 42") {
+		t.Errorf("expected the follow-up reply as output, got %q", output)
+	}
+	if !strings.Contains(client.prompts[0], "```tool:<tool_name>") {
+		t.Error("expected tool descriptions in the collaborative prompt")
+	}
+}
+
+func TestToolLoopEnforcesAllowList(t *testing.T) {
+	outside := t.TempDir()
+	config := toolTestConfig("ollama", "")
+	config.Agents[0].Tools = []string{"calc", "file"}
+	config.FileAccess = &types.FileAccessConfig{Roots: []string{t.TempDir()}}
+	runner := NewRunner(config)
+	client := &scriptedClient{responses: []string{
+		"```tool:script\n1+1\n```\n```tool:file\nexists:" + outside + "\n```",
+		"Done",
+	}}
+	runner.Clients["m"] = client
+
+	if _, err := runner.RunAgent(context.Background(), &config.Agents[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(client.prompts[1], "[script]:\nERROR: unknown or unavailable tool: script") {
+		t.Errorf("expected the unlisted tool to be refused, got %q", client.prompts[1])
+	}
+	if !strings.Contains(client.prompts[1], "file access denied") {
+		t.Errorf("expected the confined file tool to run the call, got %q", client.prompts[1])
+	}
+}
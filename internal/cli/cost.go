@@ -0,0 +1,153 @@
+/*
+Copyright © 2026 Orkflow Authors
+*/
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"Orkflow/internal/memory"
+	"Orkflow/internal/pricing"
+	"Orkflow/pkg/types"
+
+	"github.com/spf13/cobra"
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost <session-id>",
+	Short: "Show the token usage and cost of a session",
+	Long: `Cost prints a per-agent and per-model breakdown of the tokens a session's
+last run used and what they cost.
+
+Costs are computed from the recorded usage with the current pricing table:
+the built-in rates, overridden by ~/.orka/pricing.yaml (or pricing.json)
+when present. Models without a rate are listed with a cost of "-".
+
+Examples:
+  orka cost 8d6ddfb2`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		session, err := memory.LoadSession(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading session %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if session.Stats == nil || len(session.Stats.Agents) == 0 {
+			fmt.Printf("Session %s has no recorded usage.\n", session.ID)
+			return
+		}
+
+		table, err := pricing.Load()
+		if err != nil {
+			fmt.Printf("⚠️  Using built-in pricing: %v\n", err)
+		}
+		printCostReport(session, table)
+	},
+}
+
+// modelUsage is the combined usage of one provider and model
+type modelUsage struct {
+	Provider string
+	Model    string
+	Usage    types.Usage
+	Calls    int
+}
+
+// printCostReport prints the session's usage by agent, then by model
+func printCostReport(session *memory.Session, table *pricing.Table) {
+	fmt.Printf("\n💰 Cost of session %s (%s)\n\n", session.ID, session.Workflow)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tMODEL\tRUNS\tCALLS\tINPUT\tCACHED\tOUTPUT\tCOST")
+	fmt.Fprintln(w, "-----\t-----\t----\t-----\t-----\t------\t------\t----")
+
+	models := make(map[string]*modelUsage)
+	var total float64
+	unpriced := false
+	for _, agent := range session.Stats.Agents {
+		// Each call is priced at the model that served it, which differs
+		// from the agent's after a fallback
+		var cost float64
+		agentPriced := true
+		for _, call := range agent.Calls {
+			provider, model := call.Provider, call.Model
+			if model == "" {
+				provider, model = agent.Provider, agent.Model
+			}
+			callCost, ok := table.Cost(provider, model, call.Usage())
+			cost += callCost
+			agentPriced = agentPriced && ok
+
+			key := provider + "/" + model
+			m, exists := models[key]
+			if !exists {
+				m = &modelUsage{Provider: provider, Model: model}
+				models[key] = m
+			}
+			m.Usage.InputTokens += call.InputTokens
+			m.Usage.CachedInputTokens += call.CachedInputTokens
+			m.Usage.OutputTokens += call.OutputTokens
+			m.Calls++
+		}
+		total += cost
+		unpriced = unpriced || !agentPriced
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			agent.AgentID, modelLabel(agent.Provider, agent.Model), agent.Runs, len(agent.Calls),
+			agent.InputTokens, agent.CachedInputTokens, agent.OutputTokens, formatCost(cost, agentPriced))
+	}
+	w.Flush()
+
+	keys := make([]string, 0, len(models))
+	for key := range models {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tCALLS\tINPUT\tCACHED\tOUTPUT\tCOST")
+	fmt.Fprintln(w, "-----\t-----\t-----\t------\t------\t----")
+	for _, key := range keys {
+		m := models[key]
+		cost, ok := table.Cost(m.Provider, m.Model, m.Usage)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n",
+			modelLabel(m.Provider, m.Model), m.Calls,
+			m.Usage.InputTokens, m.Usage.CachedInputTokens, m.Usage.OutputTokens, formatCost(cost, ok))
+	}
+	w.Flush()
+
+	fmt.Printf("\nTotal: %d input (%d cached), %d output tokens, $%.6f\n",
+		session.Stats.InputTokens, totalCached(session.Stats), session.Stats.OutputTokens, total)
+	if unpriced {
+		fmt.Println("Some models have no rate; add them to ~/.orka/pricing.yaml to price them.")
+	}
+}
+
+func totalCached(stats *memory.RunStats) int {
+	cached := 0
+	for _, agent := range stats.Agents {
+		cached += agent.CachedInputTokens
+	}
+	return cached
+}
+
+func modelLabel(provider, model string) string {
+	if provider == "" {
+		return model
+	}
+	return provider + "/" + model
+}
+
+func formatCost(cost float64, priced bool) string {
+	if !priced {
+		return "-"
+	}
+	return fmt.Sprintf("$%.6f", cost)
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+}
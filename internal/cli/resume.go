@@ -0,0 +1,74 @@
+/*
+Copyright © 2026 Orkflow Authors
+*/
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"Orkflow/internal/memory"
+	"Orkflow/internal/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <session-id> [workflow.yaml]",
+	Short: "Resume a failed or interrupted workflow run",
+	Long: `Resume restarts a workflow from the first step that did not finish.
+
+Steps that completed before the failure are skipped, and their outputs and
+shared memory are restored from the session's checkpoint, so no LLM calls
+are repeated. The workflow file recorded in the session is used unless
+another path is given.
+
+Examples:
+  orka resume 8d6ddfb2
+  orka resume 8d6ddfb2 examples/sequential-workflow.yaml --log`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		session, err := memory.LoadSession(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading session %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		if session.Checkpoint == nil {
+			if session.Status == memory.StatusCompleted {
+				fmt.Printf("Session %s already completed. Nothing to resume.\n", session.ID)
+			} else {
+				fmt.Printf("Session %s has no checkpoint. Use 'orka run --session %s' to rerun it.\n", session.ID, session.ID)
+			}
+			return
+		}
+
+		workflowFile := session.Workflow
+		if len(args) > 1 {
+			workflowFile = args[1]
+		}
+
+		config, err := parser.ParseYAML(workflowFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing workflow %s: %v\n", workflowFile, err)
+			fmt.Fprintf(os.Stderr, "Pass the workflow path if it moved: orka resume %s <workflow.yaml>\n", session.ID)
+			os.Exit(1)
+		}
+
+		if err := ensureAPIKeys(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("📚 Resuming session: %s (%d/%d steps done)\n",
+			session.ID, session.Checkpoint.CurrentStep, session.Checkpoint.TotalSteps)
+
+		executeWorkflow(config, session, session.Checkpoint)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	resumeCmd.Flags().BoolVar(&enableLogging, "log", false, "Enable file-based execution logging")
+	resumeCmd.Flags().BoolVar(&noStream, "no-stream", false, "Disable live token streaming")
+}
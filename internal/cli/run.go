@@ -5,14 +5,21 @@ package cli
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 
+	"Orkflow/internal/agent"
 	"Orkflow/internal/engine"
 	"Orkflow/internal/logging"
 	"Orkflow/internal/memory"
 	"Orkflow/internal/parser"
+	"Orkflow/internal/pricing"
 	"Orkflow/internal/vectorstore"
 	"Orkflow/pkg/types"
 
@@ -27,6 +34,7 @@ var (
 	useModel       string
 	smartContext   bool
 	enableLogging  bool
+	noStream       bool
 )
 
 var runCmd = &cobra.Command{
@@ -48,8 +56,9 @@ Model Override:
   --use-provider    Override provider for all agents (e.g., ollama, gemini)
   --use-model       Override model name for all agents
 
-Logging:
+Output:
   --log             Enable file-based execution logging
+  --no-stream       Print agent output when it completes instead of token by token
 
 Examples:
   orka run workflow.yaml
@@ -76,18 +85,15 @@ Examples:
 
 		// Apply model/provider overrides if specified
 		if useProvider != "" || useModel != "" {
-			for name, model := range config.Models {
+			for name := range config.Models {
 				if useProvider != "" {
-					model.Provider = useProvider
 					fmt.Printf("⚡ Overriding provider for '%s' → %s\n", name, useProvider)
 				}
 				if useModel != "" {
-					model.Model = useModel
 					fmt.Printf("⚡ Overriding model for '%s' → %s\n", name, useModel)
 				}
-				model.APIKey = "" // Clear API key so it gets re-prompted for new provider
-				config.Models[name] = model
 			}
+			applyModelOverrides(config, useProvider, useModel)
 		}
 
 		// Check and prompt for missing API keys
@@ -152,208 +158,314 @@ Examples:
 			}
 		}
 
-		// Initialize logger if enabled
-		var logger *logging.Logger
-		if enableLogging {
-			var err error
-			logger, err = logging.NewLogger(session.ID, "")
-			if err != nil {
-				fmt.Printf("⚠️  Failed to create logger: %v\n", err)
-			} else {
-				fmt.Printf("📝 Logging execution to: %s\n", logger.GetFilePath())
-				defer logger.Close()
-			}
+		executeWorkflow(config, session, nil)
+	},
+}
+
+// executeWorkflow runs a parsed workflow, saving messages and checkpoints
+// to the session as it goes. A non-nil checkpoint resumes a previous run.
+func executeWorkflow(config *types.WorkflowConfig, session *memory.Session, checkpoint *memory.Checkpoint) {
+	// Initialize logger if enabled
+	var logger *logging.Logger
+	if enableLogging {
+		var err error
+		logger, err = logging.NewLogger(session.ID, "")
+		if err != nil {
+			fmt.Printf("⚠️  Failed to create logger: %v\n", err)
 		} else {
-			// Use null logger if disabled
-			logger = &logging.Logger{} // Will be handled as disabled
+			fmt.Printf("📝 Logging execution to: %s\n", logger.GetFilePath())
+			defer logger.Close()
 		}
+	} else {
+		// Use null logger if disabled
+		logger = &logging.Logger{} // Will be handled as disabled
+	}
 
-		executor := engine.NewExecutor(config)
-		if enableLogging && logger != nil {
-			executor.SetLogger(logger)
-		}
+	executor := engine.NewExecutor(config)
+	if enableLogging && logger != nil {
+		executor.SetLogger(logger)
+	}
 
+	// Price the run with the user's overrides, if any
+	table, err := pricing.Load()
+	if err != nil {
+		fmt.Printf("⚠️  Using built-in pricing: %v\n", err)
+	}
+	executor.Stats.Pricing = table
+
+	// Print tokens live, prefixed with the agent that produced them
+	if !noStream {
+		printer := &streamPrinter{}
+		executor.SetTokenCallback(printer.Token)
+	}
+
+	if checkpoint != nil {
+		// Restores the session history the original run started with
+		executor.Restore(checkpoint)
+		fmt.Printf("⏯️  Resuming after %d completed steps\n", len(checkpoint.CompletedSteps))
+	} else {
 		// Pass session history (including user prompt) to executor
 		executor.SetSessionHistory(session.GetHistory())
+	}
 
-		// Set callback to save each agent's response to session
-		executor.SetMessageCallback(func(agentID, role, content string) {
-			session.AddMessage(agentID, role, content)
-		})
+	// Agents finish concurrently in parallel and dag workflows
+	var sessionMu sync.Mutex
+
+	// Set callback to save each agent's response to session
+	executor.SetMessageCallback(func(msg memory.Message) {
+		sessionMu.Lock()
+		defer sessionMu.Unlock()
+		session.AppendMessage(msg)
+	})
+
+	// Persist a checkpoint after every completed step so a failed run can
+	// be picked up with orka resume
+	executor.SetCheckpointCallback(func(cp *memory.Checkpoint) {
+		sessionMu.Lock()
+		defer sessionMu.Unlock()
+		session.Checkpoint = cp
+		if err := session.Save(); err != nil {
+			fmt.Printf("⚠️  Could not save checkpoint: %v\n", err)
+		}
+	})
+
+	// Display workflow start banner with diagram
+	printWorkflowBanner(config)
+
+	// Ctrl+C cancels the run; a second Ctrl+C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+
+	output, err := executor.Execute(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	executor.Close()
+
+	session.Stats = executor.Stats.Snapshot()
+	if err != nil {
+		session.Checkpoint = executor.Checkpoint()
+		session.Status = memory.StatusFailed
+		session.Reason = err.Error()
+		budgetExceeded := errors.Is(err, memory.ErrBudgetExceeded)
+		if interrupted {
+			session.Status = memory.StatusCancelled
+			fmt.Println("\n🛑 Interrupted - stopping agents...")
+		} else if budgetExceeded {
+			session.Status = memory.StatusBudgetExceeded
+			fmt.Printf("\n💸 Run stopped: %v\n", err)
+		}
 
-		// Display workflow start banner with diagram
-		fmt.Println("\n" + ColorGreen + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
-		fmt.Println(ColorGreen + "║" + ColorReset + ColorBold + "                         🚀 STARTING WORKFLOW 🚀                               " + ColorReset + ColorGreen + "║" + ColorReset)
-		fmt.Println(ColorGreen + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
+		// Save partial session progress before exiting
+		if saveErr := session.Save(); saveErr != nil {
+			fmt.Printf("Warning: Could not save session: %v\n", saveErr)
+		} else {
+			fmt.Printf("💾 Partial session saved: %s (use 'orka resume %s' to pick up where it stopped)\n", session.ID, session.ID)
+		}
+		if interrupted {
+			os.Exit(130)
+		}
+		if budgetExceeded {
+			fmt.Println("   Raise the limits under budget: in the workflow to let it finish.")
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Error executing workflow: %v\n", err)
+
+		// Show helpful tip if quota exceeded or still rate limited after retries
+		kind := agent.ErrorKindOf(err)
+		if kind == types.ErrorQuota || kind == types.ErrorRateLimited {
+			fmt.Println("\n╔═══════════════════════════════════════════════════════════╗")
+			fmt.Println("║  💡 QUOTA EXCEEDED - Switch to a different model          ║")
+			fmt.Println("╠═══════════════════════════════════════════════════════════╣")
+			fmt.Println("║  Try one of these:                                        ║")
+			fmt.Println("║  --use-provider openai --use-model gpt-4o-mini            ║")
+			fmt.Println("║  Add fallbacks: [other_model] to the model in the YAML    ║")
+			fmt.Println("║  Wait a few minutes and retry with --continue             ║")
+			fmt.Println("╚═══════════════════════════════════════════════════════════╝")
+		}
 
-		if config.Workflow != nil {
-			fmt.Println()
-			if config.Workflow.Type == "sequential" && len(config.Workflow.Steps) > 0 {
-				// Sequential diagram
-				fmt.Println("                              ┌─────────────────┐")
-				for i, step := range config.Workflow.Steps {
-					agent := getAgentByID(config.Agents, step.Agent)
-					role := step.Agent
-					if agent != nil && agent.Role != "" {
-						role = agent.Role
-					}
-					if len(role) > 15 {
-						role = role[:12] + "..."
-					}
-					fmt.Printf("                              │ %-15s │\n", role)
-					fmt.Println("                              └────────┬────────┘")
-					if i < len(config.Workflow.Steps)-1 {
-						fmt.Println("                                       │")
-						fmt.Println("                                       ▼")
-						fmt.Println("                              ┌─────────────────┐")
-					}
+		// Show helpful tip for API key errors
+		errStr := err.Error()
+		if kind == types.ErrorAuth || strings.Contains(errStr, "API_KEY") || strings.Contains(errStr, "API key") {
+			fmt.Println("\n╔═══════════════════════════════════════════════════════════╗")
+			fmt.Println("║  🔑 API KEY ERROR - Check your credentials                ║")
+			fmt.Println("╠═══════════════════════════════════════════════════════════╣")
+			fmt.Println("║  Solutions:                                               ║")
+			fmt.Println("║  1. Set env: export GEMINI_API_KEY='...'                  ║")
+			fmt.Println("║  2. Set env: export OPENAI_API_KEY='...'                  ║")
+			fmt.Println("║  3. Override: --use-provider openai --use-model gpt-4o-mini║")
+			fmt.Println("╚═══════════════════════════════════════════════════════════╝")
+		}
+		os.Exit(1)
+	}
+
+	// Save session (all agent messages already added via callback)
+	session.Status = memory.StatusCompleted
+	session.Reason = ""
+	session.Checkpoint = nil
+	if err := session.Save(); err != nil {
+		fmt.Printf("Warning: Could not save session: %v\n", err)
+	}
+
+	// Index session in vector store (optional, fails silently if Ollama not running)
+	go func() {
+		store, err := vectorstore.NewChromemStoreWithOllama("nomic-embed-text")
+		if err == nil {
+			if err := vectorstore.IndexSession(store, session); err == nil {
+				fmt.Println("🧠 Session indexed for smart context.")
+			}
+			store.Close()
+		}
+	}()
+
+	// Cleanup old sessions
+	memory.CleanupOldSessions()
+
+	// Decorated final output
+	fmt.Println("\n" + ColorCyan + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
+	fmt.Println(ColorCyan + "║" + ColorReset + ColorBold + "                              ✨ WORKFLOW COMPLETE ✨                           " + ColorReset + ColorCyan + "║" + ColorReset)
+	fmt.Println(ColorCyan + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
+	fmt.Println()
+	fmt.Println(output)
+	fmt.Println()
+
+	// Stats summary
+	elapsed := session.Stats.Duration
+	cost := session.Stats.EstimatedCost
+
+	fmt.Println(ColorGreen + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
+	fmt.Printf(ColorGreen+"║"+ColorReset+"  💾 Session: "+ColorBold+"%-64s"+ColorReset+ColorGreen+" ║"+ColorReset+"\n", session.ID)
+	fmt.Printf(ColorGreen+"║"+ColorReset+"  ⏱️  Time: %-68s"+ColorGreen+" ║"+ColorReset+"\n", FormatDuration(elapsed.Seconds()))
+	if tokens := session.Stats.InputTokens + session.Stats.OutputTokens; tokens > 0 {
+		fmt.Printf(ColorGreen+"║"+ColorReset+"  🔢 Tokens: %-65s"+ColorGreen+" ║"+ColorReset+"\n",
+			fmt.Sprintf("%d in / %d out", session.Stats.InputTokens, session.Stats.OutputTokens))
+	}
+	if cost > 0 {
+		fmt.Printf(ColorGreen+"║"+ColorReset+"  💰 Est. Cost: "+ColorYellow+"$%.6f"+ColorReset+"%-56s"+ColorGreen+" ║"+ColorReset+"\n", cost, "")
+	}
+	fmt.Println(ColorGreen + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
+}
+
+// stepAgent returns the agent a step runs: its own, its switch's router or
+// its foreach template. Loops and sub-workflows have none.
+func stepAgent(step types.Step) string {
+	switch {
+	case step.Agent != "":
+		return step.Agent
+	case step.Switch != nil:
+		return step.Switch.Agent
+	case step.ForEach != nil:
+		return step.ForEach.Agent
+	}
+	return ""
+}
+
+// printWorkflowBanner prints the start banner and a diagram of the workflow
+func printWorkflowBanner(config *types.WorkflowConfig) {
+	fmt.Println("\n" + ColorGreen + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
+	fmt.Println(ColorGreen + "║" + ColorReset + ColorBold + "                         🚀 STARTING WORKFLOW 🚀                               " + ColorReset + ColorGreen + "║" + ColorReset)
+	fmt.Println(ColorGreen + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
+
+	if config.Workflow != nil {
+		fmt.Println()
+		if config.Workflow.Type == "sequential" && len(config.Workflow.Steps) > 0 {
+			// Sequential diagram
+			fmt.Println("                              ┌─────────────────┐")
+			for i, step := range config.Workflow.Steps {
+				agent := getAgentByID(config.Agents, stepAgent(step))
+				role := step.NodeID()
+				if agent != nil && agent.Role != "" {
+					role = agent.Role
 				}
-			} else if config.Workflow.Type == "parallel" && len(config.Workflow.Branches) > 0 {
-				// Parallel diagram
-				branchCount := len(config.Workflow.Branches)
-
-				// Top branches
-				fmt.Print("           ")
-				for i := 0; i < branchCount; i++ {
-					fmt.Print("┌─────────────────┐")
-					if i < branchCount-1 {
-						fmt.Print("     ")
-					}
+				if len(role) > 15 {
+					role = role[:12] + "..."
 				}
-				fmt.Println()
-
-				fmt.Print("           ")
-				for i, branchID := range config.Workflow.Branches {
-					agent := getAgentByID(config.Agents, branchID)
-					role := branchID
-					if agent != nil && agent.Role != "" {
-						role = agent.Role
-					}
-					if len(role) > 15 {
-						role = role[:12] + "..."
-					}
-					fmt.Printf("│ %-15s │", role)
-					if i < branchCount-1 {
-						fmt.Print("     ")
-					}
+				fmt.Printf("                              │ %-15s │\n", role)
+				fmt.Println("                              └────────┬────────┘")
+				if i < len(config.Workflow.Steps)-1 {
+					fmt.Println("                                       │")
+					fmt.Println("                                       ▼")
+					fmt.Println("                              ┌─────────────────┐")
+				}
+			}
+		} else if config.Workflow.Type == "parallel" && len(config.Workflow.Branches) > 0 {
+			// Parallel diagram
+			branchCount := len(config.Workflow.Branches)
+
+			// Top branches
+			fmt.Print("           ")
+			for i := 0; i < branchCount; i++ {
+				fmt.Print("┌─────────────────┐")
+				if i < branchCount-1 {
+					fmt.Print("     ")
 				}
-				fmt.Println()
+			}
+			fmt.Println()
 
-				fmt.Print("           ")
-				for i := 0; i < branchCount; i++ {
-					fmt.Print("└────────┬────────┘")
-					if i < branchCount-1 {
-						fmt.Print("     ")
-					}
+			fmt.Print("           ")
+			for i, branchID := range config.Workflow.Branches {
+				agent := getAgentByID(config.Agents, branchID)
+				role := branchID
+				if agent != nil && agent.Role != "" {
+					role = agent.Role
 				}
-				fmt.Println()
-
-				// Converging arrows
-				fmt.Print("                    ")
-				for i := 0; i < branchCount; i++ {
-					fmt.Print("│")
-					if i < branchCount-1 {
-						fmt.Print("                        ")
-					}
+				if len(role) > 15 {
+					role = role[:12] + "..."
 				}
-				fmt.Println()
-
-				fmt.Println("                    └────────────┬───────────┘")
-				fmt.Println("                                 ▼")
-
-				// Then agent
-				if config.Workflow.Then != nil {
-					agent := getAgentByID(config.Agents, config.Workflow.Then.Agent)
-					role := config.Workflow.Then.Agent
-					if agent != nil && agent.Role != "" {
-						role = agent.Role
-					}
-					if len(role) > 15 {
-						role = role[:12] + "..."
-					}
-					fmt.Println("                        ┌─────────────────┐")
-					fmt.Printf("                        │ %-15s │\n", role)
-					fmt.Println("                        └─────────────────┘")
+				fmt.Printf("│ %-15s │", role)
+				if i < branchCount-1 {
+					fmt.Print("     ")
 				}
 			}
 			fmt.Println()
-		}
 
-		output, err := executor.Execute()
-		if err != nil {
-			// Save partial session progress before exiting
-			if saveErr := session.Save(); saveErr != nil {
-				fmt.Printf("Warning: Could not save session: %v\n", saveErr)
-			} else {
-				fmt.Printf("💾 Partial session saved: %s (use --continue to retry)\n", session.ID)
-			}
-			fmt.Fprintf(os.Stderr, "Error executing workflow: %v\n", err)
-
-			// Show helpful tip if quota exceeded
-			if strings.Contains(err.Error(), "QUOTA_EXCEEDED") {
-				fmt.Println("\n╔═══════════════════════════════════════════════════════════╗")
-				fmt.Println("║  💡 QUOTA EXCEEDED - Switch to a different model          ║")
-				fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-				fmt.Println("║  Try one of these:                                        ║")
-				fmt.Println("║  --use-provider openai --use-model gpt-4o-mini            ║")
-				fmt.Println("║  Wait a few minutes and retry with --continue             ║")
-				fmt.Println("╚═══════════════════════════════════════════════════════════╝")
+			fmt.Print("           ")
+			for i := 0; i < branchCount; i++ {
+				fmt.Print("└────────┬────────┘")
+				if i < branchCount-1 {
+					fmt.Print("     ")
+				}
 			}
+			fmt.Println()
 
-			// Show helpful tip for API key errors
-			errStr := err.Error()
-			if strings.Contains(errStr, "API_KEY") || strings.Contains(errStr, "invalid_api_key") || strings.Contains(errStr, "API key") {
-				fmt.Println("\n╔═══════════════════════════════════════════════════════════╗")
-				fmt.Println("║  🔑 API KEY ERROR - Check your credentials                ║")
-				fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-				fmt.Println("║  Solutions:                                               ║")
-				fmt.Println("║  1. Set env: export GEMINI_API_KEY='...'                  ║")
-				fmt.Println("║  2. Set env: export OPENAI_API_KEY='...'                  ║")
-				fmt.Println("║  3. Override: --use-provider openai --use-model gpt-4o-mini║")
-				fmt.Println("╚═══════════════════════════════════════════════════════════╝")
+			// Converging arrows
+			fmt.Print("                    ")
+			for i := 0; i < branchCount; i++ {
+				fmt.Print("│")
+				if i < branchCount-1 {
+					fmt.Print("                        ")
+				}
 			}
-			os.Exit(1)
-		}
+			fmt.Println()
 
-		// Save session (all agent messages already added via callback)
-		if err := session.Save(); err != nil {
-			fmt.Printf("Warning: Could not save session: %v\n", err)
-		}
+			fmt.Println("                    └────────────┬───────────┘")
+			fmt.Println("                                 ▼")
 
-		// Index session in vector store (optional, fails silently if Ollama not running)
-		go func() {
-			store, err := vectorstore.NewChromemStoreWithOllama("nomic-embed-text")
-			if err == nil {
-				if err := vectorstore.IndexSession(store, session); err == nil {
-					fmt.Println("🧠 Session indexed for smart context.")
+			// Then agent
+			if config.Workflow.Then != nil {
+				agent := getAgentByID(config.Agents, config.Workflow.Then.Agent)
+				role := config.Workflow.Then.Agent
+				if agent != nil && agent.Role != "" {
+					role = agent.Role
 				}
-				store.Close()
+				if len(role) > 15 {
+					role = role[:12] + "..."
+				}
+				fmt.Println("                        ┌─────────────────┐")
+				fmt.Printf("                        │ %-15s │\n", role)
+				fmt.Println("                        └─────────────────┘")
+			}
+		} else if config.Workflow.Type == "dag" && len(config.Workflow.Steps) > 0 {
+			// DAG diagram: one line per node with its dependencies
+			for _, step := range config.Workflow.Steps {
+				deps := "(start)"
+				if len(step.DependsOn) > 0 {
+					deps = "◀── " + strings.Join(step.DependsOn, ", ")
+				}
+				fmt.Printf("                    ┌─ %-15s %s\n", step.NodeID(), deps)
 			}
-		}()
-
-		// Cleanup old sessions
-		memory.CleanupOldSessions()
-
-		// Decorated final output
-		fmt.Println("\n" + ColorCyan + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
-		fmt.Println(ColorCyan + "║" + ColorReset + ColorBold + "                              ✨ WORKFLOW COMPLETE ✨                           " + ColorReset + ColorCyan + "║" + ColorReset)
-		fmt.Println(ColorCyan + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
-		fmt.Println()
-		fmt.Println(output)
-		fmt.Println()
-
-		// Stats summary
-		elapsed := executor.Stats.GetElapsedTime()
-		cost := executor.Stats.EstimateCost()
-
-		fmt.Println(ColorGreen + "╔═══════════════════════════════════════════════════════════════════════════════╗" + ColorReset)
-		fmt.Printf(ColorGreen+"║"+ColorReset+"  💾 Session: "+ColorBold+"%-64s"+ColorReset+ColorGreen+" ║"+ColorReset+"\n", session.ID)
-		fmt.Printf(ColorGreen+"║"+ColorReset+"  ⏱️  Time: %-68s"+ColorGreen+" ║"+ColorReset+"\n", FormatDuration(elapsed.Seconds()))
-		if cost > 0 {
-			fmt.Printf(ColorGreen+"║"+ColorReset+"  💰 Est. Cost: "+ColorYellow+"$%.6f"+ColorReset+"%-56s"+ColorGreen+" ║"+ColorReset+"\n", cost, "")
 		}
-		fmt.Println(ColorGreen + "╚═══════════════════════════════════════════════════════════════════════════════╝" + ColorReset)
-	},
+		fmt.Println()
+	}
 }
 
 func init() {
@@ -365,9 +477,51 @@ func init() {
 	runCmd.Flags().StringVar(&useProvider, "use-provider", "", "Override provider for all agents (e.g., ollama, gemini)")
 	runCmd.Flags().StringVar(&useModel, "use-model", "", "Override model for all agents (e.g., llama3, gemini-2.5-flash)")
 	runCmd.Flags().BoolVar(&enableLogging, "log", false, "Enable file-based execution logging")
+	runCmd.Flags().BoolVar(&noStream, "no-stream", false, "Disable live token streaming")
 }
 
+// applyModelOverrides points every model of a workflow and its
+// sub-workflows at the given provider and model, where set
+func applyModelOverrides(config *types.WorkflowConfig, provider, modelName string) {
+	for name, model := range config.Models {
+		if provider != "" {
+			model.Provider = provider
+		}
+		if modelName != "" {
+			model.Model = modelName
+		}
+		model.APIKey = "" // Clear API key so it gets re-prompted for new provider
+		config.Models[name] = model
+	}
+	if config.Workflow != nil {
+		for _, sub := range config.Workflow.SubWorkflows() {
+			applyModelOverrides(sub, provider, modelName)
+		}
+	}
+}
+
+// ensureAPIKeys resolves the API keys of a workflow's models, and then of
+// its sub-workflows' models. Sub-workflow models reuse the key already
+// resolved for the same provider before falling back to the environment,
+// the CLI config or a prompt.
 func ensureAPIKeys(config *types.WorkflowConfig) error {
+	if err := resolveAPIKeys(config); err != nil {
+		return err
+	}
+	if config.Workflow == nil {
+		return nil
+	}
+	for _, sub := range config.Workflow.SubWorkflows() {
+		sub.Models = types.InheritAPIKeys(sub.Models, config.Models)
+		if err := ensureAPIKeys(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAPIKeys fills in the missing API keys of a workflow's own models
+func resolveAPIKeys(config *types.WorkflowConfig) error {
 	cliConfig := LoadEffectiveConfig()
 
 	for name, model := range config.Models {
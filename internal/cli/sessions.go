@@ -76,6 +76,12 @@ Examples:
 		fmt.Printf("║  📁 Workflow: %-43s ║\n", truncateStr(session.Workflow, 43))
 		fmt.Printf("║  🕐 Created: %-44s ║\n", session.CreatedAt.Format("Jan 02 15:04"))
 		fmt.Printf("║  📨 Messages: %-43d ║\n", len(session.Messages))
+		if session.Status != "" {
+			fmt.Printf("║  🏷️  Status: %-44s ║\n", session.Status)
+		}
+		if session.Reason != "" {
+			fmt.Printf("║  ❗ Reason: %-45s ║\n", truncateStr(session.Reason, 45))
+		}
 		fmt.Println("╠═══════════════════════════════════════════════════════════╣")
 
 		// Display workflow visualization
@@ -120,8 +126,15 @@ Examples:
 						icon = "👀"
 					}
 
-					fmt.Printf("║  %s Step %d: %-45s ║\n", icon, i+1, truncateStr(msg.AgentID, 45))
+					label := msg.AgentID
+					if msg.Iteration > 0 {
+						label = fmt.Sprintf("%s (iteration %d)", msg.AgentID, msg.Iteration)
+					}
+					fmt.Printf("║  %s Step %d: %-45s ║\n", icon, i+1, truncateStr(label, 45))
 					fmt.Printf("║     Role: %-49s ║\n", truncateStr(msg.Role, 49))
+					if msg.Model != "" {
+						fmt.Printf("║     Model: %-48s ║\n", truncateStr(msg.Model, 48))
+					}
 
 					if i < len(session.Messages)-1 {
 						fmt.Println("║                         │                               ║")
@@ -131,6 +144,20 @@ Examples:
 			}
 		}
 
+		// Revision history for agents that ran inside loops
+		if history := revisionHistory(session.Messages); len(history) > 0 {
+			fmt.Println("╠═══════════════════════════════════════════════════════════╣")
+			fmt.Println("║                     REVISION HISTORY                      ║")
+			fmt.Println("╠═══════════════════════════════════════════════════════════╣")
+			for _, rev := range history {
+				fmt.Printf("║  🔁 %-54s ║\n", truncateStr(rev.AgentID, 54))
+				for _, msg := range rev.Messages {
+					line := fmt.Sprintf("iteration %d · %s · %d chars", msg.Iteration, msg.Timestamp.Format("15:04:05"), len(msg.Content))
+					fmt.Printf("║     %-53s ║\n", truncateStr(line, 53))
+				}
+			}
+		}
+
 		// Only show message details if --workflow flag is not set
 		if !showWorkflowOnly {
 			fmt.Println("╠═══════════════════════════════════════════════════════════╣")
@@ -150,7 +177,11 @@ Examples:
 				}
 
 				fmt.Printf("┌──────────────────────────────────────────────────────────────┐\n")
-				fmt.Printf("│ %s Message %d: [%s] - %s\n", icon, i+1, msg.AgentID, msg.Role)
+				if msg.Iteration > 0 {
+					fmt.Printf("│ %s Message %d: [%s] - %s (iteration %d)\n", icon, i+1, msg.AgentID, msg.Role, msg.Iteration)
+				} else {
+					fmt.Printf("│ %s Message %d: [%s] - %s\n", icon, i+1, msg.AgentID, msg.Role)
+				}
 				fmt.Printf("│ 🕐 %s\n", msg.Timestamp.Format("15:04:05"))
 				fmt.Printf("├──────────────────────────────────────────────────────────────┤\n")
 
@@ -178,6 +209,33 @@ Examples:
 	},
 }
 
+// agentRevisions groups the loop iterations of one agent
+type agentRevisions struct {
+	AgentID  string
+	Messages []memory.Message
+}
+
+// revisionHistory collects, per agent, the messages produced inside loop
+// iterations, in the order the agents first appear.
+func revisionHistory(msgs []memory.Message) []agentRevisions {
+	var history []agentRevisions
+	index := make(map[string]int)
+
+	for _, msg := range msgs {
+		if msg.Iteration == 0 {
+			continue
+		}
+		i, ok := index[msg.AgentID]
+		if !ok {
+			i = len(history)
+			index[msg.AgentID] = i
+			history = append(history, agentRevisions{AgentID: msg.AgentID})
+		}
+		history[i].Messages = append(history[i].Messages, msg)
+	}
+	return history
+}
+
 func truncateStr(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// streamPrinter prints streamed tokens as they arrive. Every line starts
+// with the agent's ID, and a new line is started whenever output switches
+// to another agent, so parallel branches stay readable.
+type streamPrinter struct {
+	mu        sync.Mutex
+	current   string // Agent whose line is open
+	lineStart bool
+}
+
+// Token prints one chunk of an agent's output. An empty token ends the
+// agent's stream.
+func (p *streamPrinter) Token(agentID, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if token == "" {
+		if p.current == agentID {
+			if !p.lineStart {
+				fmt.Println()
+			}
+			p.current = ""
+		}
+		return
+	}
+
+	if p.current != agentID {
+		if p.current != "" && !p.lineStart {
+			fmt.Println()
+		}
+		p.current = agentID
+		p.lineStart = true
+	}
+
+	for token != "" {
+		if p.lineStart {
+			fmt.Printf("%s[%s]%s ", ColorDim, agentID, ColorReset)
+			p.lineStart = false
+		}
+		i := strings.IndexByte(token, '\n')
+		if i < 0 {
+			fmt.Print(token)
+			return
+		}
+		fmt.Print(token[:i+1])
+		token = token[i+1:]
+		p.lineStart = true
+	}
+}
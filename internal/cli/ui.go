@@ -60,24 +60,3 @@ func FormatDuration(seconds float64) string {
 	secs := int(seconds) % 60
 	return fmt.Sprintf("%dm %ds", mins, secs)
 }
-
-// ModelPricing stores cost per 1M tokens (input/output)
-var ModelPricing = map[string]struct{ Input, Output float64 }{
-	"gpt-4o":           {2.50, 10.00},
-	"gpt-4o-mini":      {0.15, 0.60},
-	"gpt-4-turbo":      {10.00, 30.00},
-	"gpt-3.5-turbo":    {0.50, 1.50},
-	"gemini-2.0-flash": {0.075, 0.30},
-	"gemini-1.5-pro":   {1.25, 5.00},
-}
-
-// EstimateCost calculates cost based on token counts
-func EstimateCost(model string, inputTokens, outputTokens int) float64 {
-	pricing, ok := ModelPricing[model]
-	if !ok {
-		return 0
-	}
-	inputCost := float64(inputTokens) / 1000000 * pricing.Input
-	outputCost := float64(outputTokens) / 1000000 * pricing.Output
-	return inputCost + outputCost
-}
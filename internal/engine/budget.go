@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+
+	"Orkflow/internal/memory"
+)
+
+// checkBudget runs before every LLM call. Once the run has reached one of
+// the workflow's budget limits it aborts shared memory, so agents waiting on
+// required keys stop too, and refuses the call. Calls already in flight in
+// parallel branches still finish, so a limit can be overshot slightly.
+func (e *Executor) checkBudget(agentID string) error {
+	budget := e.Config.Budget
+	if budget == nil {
+		return nil
+	}
+
+	e.budgetMu.Lock()
+	defer e.budgetMu.Unlock()
+	if e.budgetErr != nil {
+		return e.budgetErr
+	}
+
+	tokens, calls, cost := e.Stats.Totals()
+	var reason string
+	switch {
+	case budget.MaxCalls > 0 && calls >= budget.MaxCalls:
+		reason = fmt.Sprintf("max_calls %d reached", budget.MaxCalls)
+	case budget.MaxTokens > 0 && tokens >= budget.MaxTokens:
+		reason = fmt.Sprintf("max_tokens %d reached (%d used)", budget.MaxTokens, tokens)
+	case budget.MaxCostUSD > 0 && cost >= budget.MaxCostUSD:
+		reason = fmt.Sprintf("max_cost_usd $%.4f reached ($%.4f spent)", budget.MaxCostUSD, cost)
+	default:
+		return nil
+	}
+
+	e.budgetErr = fmt.Errorf("%w: %s", memory.ErrBudgetExceeded, reason)
+	fmt.Printf("💸 Budget exceeded before %s's call: %s\n", agentID, reason)
+	if e.Logger != nil {
+		e.Logger.LogAgent(agentID, "BUDGET_EXCEEDED", reason)
+	}
+	e.SharedMemory.Abort(e.budgetErr.Error())
+	return e.budgetErr
+}
+
+// budgetExceeded returns the error of the budget limit that stopped the run,
+// or nil when none was reached
+func (e *Executor) budgetExceeded() error {
+	e.budgetMu.Lock()
+	defer e.budgetMu.Unlock()
+	return e.budgetErr
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+func TestExecute_AgentTimeout(t *testing.T) {
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "slow", Timeout: 50 * time.Millisecond}},
+		Workflow: &types.WorkflowSpec{
+			Type:  "sequential",
+			Steps: []types.Step{{Agent: "slow"}},
+		},
+	}, newStubClient(2*time.Second))
+
+	start := time.Now()
+	_, err := executor.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("expected agent timeout error, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timed out agent should not be retried, took %v", elapsed)
+	}
+}
+
+func TestExecute_WorkflowTimeout(t *testing.T) {
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}, {ID: "b"}},
+		Workflow: &types.WorkflowSpec{
+			Type:    "sequential",
+			Timeout: 50 * time.Millisecond,
+			Steps:   []types.Step{{Agent: "a"}, {Agent: "b"}},
+		},
+	}, newStubClient(2*time.Second))
+
+	_, err := executor.Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "workflow timed out") {
+		t.Fatalf("expected workflow timeout error, got %v", err)
+	}
+}
+
+func TestExecute_CancelWakesWaitingAgents(t *testing.T) {
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "worker"},
+			{ID: "consumer", Requires: []string{"never_published"}},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type:     "parallel",
+			Branches: []string{"worker", "consumer"},
+		},
+	}, newStubClient(2*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := executor.Execute(ctx); err == nil {
+		t.Fatal("expected cancellation error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation did not stop the workflow promptly, took %v", elapsed)
+	}
+	if !executor.SharedMemory.IsAborted() {
+		t.Error("expected shared memory to be aborted")
+	}
+}
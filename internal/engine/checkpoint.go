@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"Orkflow/internal/agent"
+	"Orkflow/internal/memory"
+)
+
+// Keys for checkpointed steps that are not identified by a node ID
+const (
+	checkpointThen          = "then"
+	checkpointCollaboration = "collaboration"
+	checkpointSupervisor    = "supervisor"
+)
+
+// SetCheckpointCallback sets a callback that receives a checkpoint after
+// every completed top-level step
+func (e *Executor) SetCheckpointCallback(callback func(cp *memory.Checkpoint)) {
+	e.checkpointCallback = callback
+}
+
+// Restore loads a checkpoint from a previous run. Steps it lists as
+// completed are skipped, and the context history and shared memory they
+// produced are put back so later steps see the same inputs. The usage
+// recorded so far is carried over, so totals and budgets cover both runs.
+func (e *Executor) Restore(cp *memory.Checkpoint) {
+	e.cpMu.Lock()
+	defer e.cpMu.Unlock()
+
+	e.completed = make(map[string]bool, len(cp.CompletedSteps))
+	e.completedOrder = append([]string{}, cp.CompletedSteps...)
+	for _, key := range cp.CompletedSteps {
+		e.completed[key] = true
+	}
+	e.State.CurrentStep = cp.CurrentStep
+
+	for _, out := range cp.History {
+		e.Runner.Context.AddIterationOutput(out.AgentID, out.Response, out.Iteration)
+	}
+	for key, value := range cp.Shared {
+		e.SharedMemory.Set(key, value)
+	}
+	e.Runner.SetSessionHistory(cp.SessionHistory)
+	if cp.Stats != nil {
+		e.Stats.Restore(cp.Stats)
+	}
+}
+
+// isCompleted reports whether a step finished in the run being resumed and
+// announces the skip
+func (e *Executor) isCompleted(key string) bool {
+	e.cpMu.Lock()
+	done := e.completed[key]
+	e.cpMu.Unlock()
+
+	if done {
+		fmt.Printf("⏩ Skipping %s (completed in previous run)\n", key)
+		if e.Logger != nil {
+			e.Logger.LogAgent(key, "RESUME_SKIPPED", "completed in previous run")
+		}
+	}
+	return done
+}
+
+// markCompleted records a finished step and emits a checkpoint
+func (e *Executor) markCompleted(key string) {
+	e.cpMu.Lock()
+	defer e.cpMu.Unlock()
+
+	if e.completed == nil {
+		e.completed = make(map[string]bool)
+	}
+	e.completed[key] = true
+	e.completedOrder = append(e.completedOrder, key)
+
+	if e.checkpointCallback != nil {
+		e.checkpointCallback(e.checkpoint())
+	}
+}
+
+// Checkpoint returns a checkpoint of the run so far
+func (e *Executor) Checkpoint() *memory.Checkpoint {
+	e.cpMu.Lock()
+	defer e.cpMu.Unlock()
+	return e.checkpoint()
+}
+
+// checkpoint builds a checkpoint of the current run. Callers hold cpMu.
+func (e *Executor) checkpoint() *memory.Checkpoint {
+	outputs := e.Runner.Context.Snapshot()
+	history := make([]memory.CheckpointOutput, 0, len(outputs))
+	for _, out := range outputs {
+		history = append(history, checkpointOutput(out))
+	}
+
+	return &memory.Checkpoint{
+		CompletedSteps: append([]string{}, e.completedOrder...),
+		CurrentStep:    e.State.CurrentStep,
+		TotalSteps:     e.State.TotalSteps,
+		SessionHistory: e.Runner.SessionHistory,
+		History:        history,
+		Shared:         e.SharedMemory.Snapshot(),
+		Stats:          e.Stats.Snapshot(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+func checkpointOutput(out agent.AgentOutput) memory.CheckpointOutput {
+	return memory.CheckpointOutput{
+		AgentID:   out.AgentID,
+		Response:  out.Response,
+		Iteration: out.Iteration,
+		Timestamp: out.Timestamp,
+	}
+}
+
+// sequentialKey identifies a top-level sequential step. The index keeps
+// repeated agents apart.
+func sequentialKey(i int, id string) string {
+	return fmt.Sprintf("%d:%s", i+1, id)
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+// roundTrip mimics saving a checkpoint in the session file and loading it back
+func roundTrip(t *testing.T, cp *memory.Checkpoint) *memory.Checkpoint {
+	t.Helper()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded memory.Checkpoint
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	return &loaded
+}
+
+func failOn(goal string) func(string) error {
+	return func(g string) error {
+		if g == goal {
+			return errors.New("provider down")
+		}
+		return nil
+	}
+}
+
+func sequentialConfig() *types.WorkflowConfig {
+	return &types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "a", Outputs: []string{"notes"}},
+			{ID: "b"},
+			{ID: "c"},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type:  "sequential",
+			Steps: []types.Step{{Agent: "a"}, {Agent: "b"}, {Agent: "c"}},
+		},
+	}
+}
+
+func TestResume_Sequential(t *testing.T) {
+	first := newStubClient(0)
+	first.fail = failOn("b")
+	executor := newTestExecutor(sequentialConfig(), first)
+
+	var saved *memory.Checkpoint
+	executor.SetCheckpointCallback(func(cp *memory.Checkpoint) { saved = cp })
+
+	if _, err := executor.Execute(context.Background()); err == nil {
+		t.Fatal("expected the first run to fail at b")
+	}
+	if saved == nil || strings.Join(saved.CompletedSteps, ",") != "1:a" {
+		t.Fatalf("expected a checkpoint after a, got %+v", saved)
+	}
+
+	second := newStubClient(0)
+	resumed := newTestExecutor(sequentialConfig(), second)
+	resumed.Restore(roundTrip(t, saved))
+
+	output, err := resumed.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("resumed Execute() error: %v", err)
+	}
+	if output != "done:c" {
+		t.Errorf("expected final output from c, got %q", output)
+	}
+	if _, ran := second.prompts["a"]; ran {
+		t.Error("completed step a should not run again")
+	}
+	if !strings.Contains(second.prompts["b"], "[a]:\ndone:a") {
+		t.Error("b should see a's restored output")
+	}
+	if notes, _ := resumed.SharedMemory.Get("notes"); notes != "done:a" {
+		t.Errorf("expected restored shared memory, got %v", notes)
+	}
+	if got := resumed.Checkpoint().CompletedSteps; strings.Join(got, ",") != "1:a,2:b,3:c" {
+		t.Errorf("unexpected completed steps %v", got)
+	}
+	if resumed.State.CurrentStep != 3 {
+		t.Errorf("expected 3 completed steps, got %d", resumed.State.CurrentStep)
+	}
+}
+
+func TestResume_DAG(t *testing.T) {
+	dagConfig := func() *types.WorkflowConfig {
+		return &types.WorkflowConfig{
+			Agents: []types.Agent{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+			Workflow: &types.WorkflowSpec{
+				Type: "dag",
+				Steps: []types.Step{
+					{Agent: "a"},
+					{Agent: "b", DependsOn: []string{"a"}},
+					{Agent: "c", DependsOn: []string{"b"}},
+				},
+			},
+		}
+	}
+
+	first := newStubClient(0)
+	first.fail = failOn("b")
+	executor := newTestExecutor(dagConfig(), first)
+	if _, err := executor.Execute(context.Background()); err == nil {
+		t.Fatal("expected the first run to fail at b")
+	}
+
+	second := newStubClient(0)
+	resumed := newTestExecutor(dagConfig(), second)
+	resumed.Restore(roundTrip(t, executor.Checkpoint()))
+
+	if _, err := resumed.Execute(context.Background()); err != nil {
+		t.Fatalf("resumed Execute() error: %v", err)
+	}
+	if _, ran := second.prompts["a"]; ran {
+		t.Error("completed node a should not run again")
+	}
+	if _, ran := second.prompts["c"]; !ran {
+		t.Error("expected c to run after b on resume")
+	}
+}
+
+func TestResume_KeepsStats(t *testing.T) {
+	first := newStubClient(0)
+	first.fail = failOn("b")
+	executor := newTestExecutor(sequentialConfig(), first)
+	if _, err := executor.Execute(context.Background()); err == nil {
+		t.Fatal("expected the first run to fail at b")
+	}
+	saved := roundTrip(t, executor.Checkpoint())
+	if saved.Stats == nil || len(saved.Stats.Agents) == 0 {
+		t.Fatalf("expected the checkpoint to carry stats, got %+v", saved.Stats)
+	}
+	_, before, _ := executor.Stats.Totals()
+
+	resumed := newTestExecutor(sequentialConfig(), newStubClient(0))
+	resumed.Restore(saved)
+	if _, err := resumed.Execute(context.Background()); err != nil {
+		t.Fatalf("resumed Execute() error: %v", err)
+	}
+	tokens, calls, _ := resumed.Stats.Totals()
+	if calls != before+2 || tokens != (before+2)*15 {
+		t.Errorf("expected %d calls and %d tokens across both runs, got %d and %d", before+2, (before+2)*15, calls, tokens)
+	}
+	stats := resumed.Stats.Snapshot()
+	if len(stats.Agents) != 3 || stats.Agents[0].AgentID != "a" || stats.Agents[0].InputTokens != 10 {
+		t.Errorf("expected a's usage from the first run to be kept, got %+v", stats.Agents)
+	}
+
+	// The budget counts the first run's calls too: only b fits
+	config := sequentialConfig()
+	config.Budget = &types.BudgetConfig{MaxCalls: before + 1}
+	second := newStubClient(0)
+	limited := newTestExecutor(config, second)
+	limited.Restore(roundTrip(t, saved))
+	if _, err := limited.Execute(context.Background()); !errors.Is(err, memory.ErrBudgetExceeded) {
+		t.Fatalf("expected the budget to stop the resumed run, got %v", err)
+	}
+	if _, ran := second.prompts["b"]; !ran {
+		t.Error("expected b to run within the budget")
+	}
+	if _, ran := second.prompts["c"]; ran {
+		t.Error("c should have been stopped by the budget")
+	}
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"Orkflow/internal/memory"
+)
+
+// DefaultCollaborativeTurns is the shared turn budget when max_turns is unset
+const DefaultCollaborativeTurns = 10
+
+// executeCollaborative runs the collaboration and then the optional then
+// step, checkpointing each.
+func (e *Executor) executeCollaborative(ctx context.Context) (string, error) {
+	e.State.Start()
+
+	wf := e.Config.Workflow
+
+	if !e.isCompleted(checkpointCollaboration) {
+		if err := e.runCollaboration(ctx); err != nil {
+			e.State.Fail(err)
+			return "", err
+		}
+		e.State.NextStep()
+		e.markCompleted(checkpointCollaboration)
+	}
+
+	if wf.Then != nil && !e.isCompleted(checkpointThen) {
+		thenAgent := e.Runner.GetAgent(wf.Then.Agent)
+		if thenAgent == nil {
+			err := fmt.Errorf("then agent not found: %s", wf.Then.Agent)
+			e.State.Fail(err)
+			return "", err
+		}
+
+		if err := e.runStep(ctx, wf.Then, 0); err != nil {
+			e.State.Fail(err)
+			return "", err
+		}
+		e.State.NextStep()
+		e.markCompleted(checkpointThen)
+	}
+
+	e.State.Complete()
+	return e.Runner.GetFinalOutput(), nil
+}
+
+// runCollaboration runs the collaborators concurrently over a shared
+// MessageChannel until the termination policy is met or the global turn
+// budget runs out.
+func (e *Executor) runCollaboration(ctx context.Context) error {
+	wf := e.Config.Workflow
+	participants := wf.Collaborators
+
+	turnBudget := wf.MaxTurns
+	if turnBudget <= 0 {
+		turnBudget = DefaultCollaborativeTurns
+	}
+
+	channel := memory.NewMessageChannel(100)
+	defer channel.Close()
+	channel.SetTurnBudget(turnBudget)
+	channel.OnDone(e.terminationPolicy(channel))
+
+	fmt.Printf("🤝 Collaborative workflow: %d agents, %d shared turns, termination=%s\n",
+		len(participants), turnBudget, terminationName(wf.Termination))
+
+	// Subscribe everyone up front so early messages are not dropped
+	for _, id := range participants {
+		channel.Subscribe(id)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for _, id := range participants {
+		agentDef := e.Runner.GetAgent(id)
+		if agentDef == nil {
+			return fmt.Errorf("agent not found: %s", id)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := e.Runner.RunCollaborativeAgent(ctx, agentDef, channel)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				// Stop the other collaborators and wake up waiting agents
+				channel.Close()
+				if e.SharedMemory != nil {
+					e.SharedMemory.Abort(err.Error())
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	fmt.Printf("🤝 Collaboration finished after %d turns\n", channel.TurnsUsed())
+	if e.Logger != nil {
+		e.Logger.Log("Collaboration finished after %d/%d turns", channel.TurnsUsed(), turnBudget)
+	}
+	return nil
+}
+
+// terminationPolicy returns the DONE handler that closes the channel once the
+// workflow's termination policy is satisfied.
+func (e *Executor) terminationPolicy(channel *memory.MessageChannel) func(agentID string, doneCount int) {
+	wf := e.Config.Workflow
+	participants := len(wf.Collaborators)
+
+	quorum := wf.Quorum
+	if quorum <= 0 {
+		quorum = participants/2 + 1
+	}
+
+	return func(agentID string, doneCount int) {
+		finished := false
+		switch wf.Termination {
+		case "quorum":
+			finished = doneCount >= quorum
+		case "moderator":
+			finished = agentID == wf.Moderator
+		default:
+			finished = doneCount >= participants
+		}
+
+		if finished {
+			fmt.Printf("🏁 Termination policy '%s' satisfied (%s signaled DONE)\n", terminationName(wf.Termination), agentID)
+			channel.Close()
+		}
+	}
+}
+
+func terminationName(policy string) string {
+	if policy == "" {
+		return "all"
+	}
+	return policy
+}
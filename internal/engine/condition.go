@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+)
+
+// conditionEnv builds the variables visible to when/until expressions:
+//
+//	memory  - shared memory keys, e.g. memory.verdict == "approve"
+//	outputs - latest response per agent, e.g. outputs.reviewer contains "LGTM"
+//
+// vars adds extra top-level variables such as the loop iteration.
+func (e *Executor) conditionEnv(vars map[string]interface{}) map[string]interface{} {
+	env := map[string]interface{}{
+		"memory":  map[string]interface{}{},
+		"outputs": e.Runner.Context.Outputs(),
+	}
+	if e.SharedMemory != nil {
+		env["memory"] = e.SharedMemory.Snapshot()
+	}
+	for k, v := range vars {
+		env[k] = v
+	}
+	return env
+}
+
+// evalCondition evaluates a boolean expression against the current run state
+func (e *Executor) evalCondition(expression string, vars map[string]interface{}) (bool, error) {
+	env := e.conditionEnv(vars)
+
+	program, err := expr.Compile(expression, expr.Env(env), expr.AsBool())
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", expression, err)
+	}
+
+	result, err := expr.Run(program, env)
+	if err != nil {
+		return false, fmt.Errorf("condition %q failed: %w", expression, err)
+	}
+	return result.(bool), nil
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Orkflow/internal/agent"
+	"Orkflow/pkg/types"
+)
+
+// dagResult is reported by a node goroutine when it finishes
+type dagResult struct {
+	id  string
+	err error
+}
+
+// executeDAG runs workflow steps as a dependency graph. A node starts as soon
+// as every node it depends_on has completed, with at most MaxParallel nodes
+// in flight (unlimited when MaxParallel <= 0).
+func (e *Executor) executeDAG(ctx context.Context) (string, error) {
+	e.State.Start()
+
+	steps := e.Config.Workflow.Steps
+	limit := e.Config.Workflow.MaxParallel
+
+	// Count unmet dependencies and build the reverse edges
+	pending := make(map[string]int, len(steps))
+	dependents := make(map[string][]*types.Step, len(steps))
+	for i := range steps {
+		step := &steps[i]
+		pending[step.NodeID()] = len(step.DependsOn)
+		for _, dep := range step.DependsOn {
+			dependents[dep] = append(dependents[dep], step)
+		}
+	}
+
+	// Nodes that finished in the run being resumed are done up front
+	resumed := make(map[string]bool)
+	for i := range steps {
+		id := steps[i].NodeID()
+		if e.isCompleted(id) {
+			resumed[id] = true
+			for _, next := range dependents[id] {
+				pending[next.NodeID()]--
+			}
+		}
+	}
+
+	var ready []*types.Step
+	for i := range steps {
+		if !resumed[steps[i].NodeID()] && pending[steps[i].NodeID()] == 0 {
+			ready = append(ready, &steps[i])
+		}
+	}
+
+	if len(ready) == 0 && len(resumed) < len(steps) {
+		err := fmt.Errorf("dag has no entry nodes")
+		e.State.Fail(err)
+		return "", err
+	}
+
+	done := make(chan dagResult)
+	running := 0
+	completed := len(resumed)
+	var firstErr error
+
+	for completed < len(steps) {
+		// Launch everything that is ready, up to the parallelism limit
+		for firstErr == nil && len(ready) > 0 && (limit <= 0 || running < limit) {
+			step := ready[0]
+			ready = ready[1:]
+			running++
+
+			go func(step *types.Step) {
+				done <- dagResult{id: step.NodeID(), err: e.runDAGNode(ctx, step)}
+			}(step)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				// Abort shared memory to wake up waiting agents
+				if e.SharedMemory != nil {
+					e.SharedMemory.Abort(res.err.Error())
+				}
+			}
+			continue
+		}
+		if firstErr != nil {
+			continue
+		}
+
+		completed++
+		e.State.NextStep()
+		e.markCompleted(res.id)
+
+		for _, next := range dependents[res.id] {
+			pending[next.NodeID()]--
+			if pending[next.NodeID()] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		e.State.Fail(firstErr)
+		return "", firstErr
+	}
+
+	if completed < len(steps) {
+		err := fmt.Errorf("dag stalled after %d of %d nodes", completed, len(steps))
+		e.State.Fail(err)
+		return "", err
+	}
+
+	e.State.Complete()
+	return e.dagOutput(steps, dependents), nil
+}
+
+// dagOutput returns the result of a dag: the output of each sink node (one
+// nothing depends on) in declaration order. The last output recorded would
+// depend on which parallel branch happened to finish last.
+func (e *Executor) dagOutput(steps []types.Step, dependents map[string][]*types.Step) string {
+	history := e.Runner.Context.Snapshot()
+	var labelled []string
+	var outputs []string
+	for i := range steps {
+		step := &steps[i]
+		if len(dependents[step.NodeID()]) > 0 {
+			continue
+		}
+		output, ok := e.nodeOutput(step, history)
+		if !ok {
+			continue // Skipped by its when condition
+		}
+		outputs = append(outputs, output)
+		labelled = append(labelled, fmt.Sprintf("[%s]:\n%s", step.NodeID(), output))
+	}
+	if len(outputs) == 1 {
+		return outputs[0]
+	}
+	return strings.Join(labelled, "\n\n")
+}
+
+// nodeOutput returns the latest output a step produced. A foreach without a
+// then step produces its collected results.
+func (e *Executor) nodeOutput(step *types.Step, history []agent.AgentOutput) (string, bool) {
+	if fe := step.ForEach; fe != nil && fe.Then == nil {
+		results, ok := e.SharedMemory.Get(fe.ResultsKey())
+		if !ok {
+			return "", false
+		}
+		data, err := json.Marshal(results)
+		if err != nil {
+			return fmt.Sprintf("%v", results), true
+		}
+		return string(data), true
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if producedBy(step, history[i].AgentID) {
+			return history[i].Response, true
+		}
+	}
+	return "", false
+}
+
+// producedBy reports whether an output recorded under agentID came from step
+func producedBy(step *types.Step, agentID string) bool {
+	switch {
+	case step.Workflow != "":
+		return agentID == step.NodeID()
+	case step.Loop != nil:
+		return anyProducedBy(step.Loop.Steps, agentID)
+	case step.ForEach != nil:
+		return step.ForEach.Then != nil && producedBy(step.ForEach.Then, agentID)
+	case step.Switch != nil:
+		if agentID == step.Switch.Agent || anyProducedBy(step.Switch.Default, agentID) {
+			return true
+		}
+		for _, steps := range step.Switch.Cases {
+			if anyProducedBy(steps, agentID) {
+				return true
+			}
+		}
+		return false
+	}
+	return agentID == step.Agent
+}
+
+func anyProducedBy(steps []types.Step, agentID string) bool {
+	for i := range steps {
+		if producedBy(&steps[i], agentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// runDAGNode runs a single dag node. A node skipped by its when condition
+// still counts as complete so its dependents can run.
+func (e *Executor) runDAGNode(ctx context.Context, step *types.Step) error {
+	return e.runStep(ctx, step, 0)
+}
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -19,12 +21,25 @@ type Executor struct {
 	MCPClient    *mcp.Client
 	Logger       *logging.Logger
 	Stats        *ExecutionStats
+
+	// Checkpointing
+	cpMu               sync.Mutex
+	completed          map[string]bool
+	completedOrder     []string
+	checkpointCallback func(cp *memory.Checkpoint)
+
+	// Budget
+	budgetMu  sync.Mutex
+	budgetErr error // Set once a budget limit is reached
 }
 
 func NewExecutor(config *types.WorkflowConfig) *Executor {
 	totalSteps := 0
 	if config.Workflow != nil {
 		totalSteps = len(config.Workflow.Steps) + len(config.Workflow.Branches)
+		if len(config.Workflow.Collaborators) > 0 {
+			totalSteps++
+		}
 		if config.Workflow.Then != nil {
 			totalSteps++
 		}
@@ -43,6 +58,8 @@ func NewExecutor(config *types.WorkflowConfig) *Executor {
 		SharedMemory: sharedMem,
 		Stats:        NewExecutionStats(),
 	}
+	runner.Stats = executor.Stats
+	runner.CallGuard = executor.checkBudget
 
 	// Connect to MCP servers if defined
 	if len(config.MCPServers) > 0 {
@@ -56,8 +73,8 @@ func NewExecutor(config *types.WorkflowConfig) *Executor {
 			if err := executor.MCPClient.Connect(name, mcpConfig); err != nil {
 				fmt.Printf("⚠️  Failed to connect to MCP server '%s': %v\n", name, err)
 			} else {
-				// Register MCP tools with the tool registry
-				mcp.RegisterMCPTools(executor.MCPClient, name)
+				// Register MCP tools with this run's tool registry
+				mcp.RegisterMCPTools(executor.MCPClient, name, runner.Tools)
 			}
 		}
 	}
@@ -66,7 +83,7 @@ func NewExecutor(config *types.WorkflowConfig) *Executor {
 }
 
 // SetSessionHistory passes previous session context to the runner
-func (e *Executor) SetSessionHistory(history string) {
+func (e *Executor) SetSessionHistory(history []types.ChatMessage) {
 	e.Runner.SetSessionHistory(history)
 }
 
@@ -77,50 +94,88 @@ func (e *Executor) SetLogger(logger *logging.Logger) {
 }
 
 // SetMessageCallback sets callback for when agents complete
-func (e *Executor) SetMessageCallback(callback func(agentID, role, content string)) {
+func (e *Executor) SetMessageCallback(callback func(msg memory.Message)) {
 	e.Runner.MessageCallback = callback
 }
 
-func (e *Executor) Execute() (string, error) {
+// SetTokenCallback streams agent output to callback as it is generated. An
+// empty token marks the end of an agent's stream.
+func (e *Executor) SetTokenCallback(callback func(agentID, token string)) {
+	e.Runner.TokenCallback = callback
+}
+
+// Execute runs the workflow until it finishes or ctx is done. The workflow
+// timeout, if set, is applied on top of ctx. When the run is cancelled,
+// shared memory is aborted so agents waiting on required keys stop too.
+// When a budget limit stopped the run, the budget error is returned in place
+// of the failures it caused.
+func (e *Executor) Execute(ctx context.Context) (string, error) {
+	if e.Config.Workflow != nil && e.Config.Workflow.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Config.Workflow.Timeout)
+		defer cancel()
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		e.SharedMemory.Abort(ctx.Err().Error())
+	})
+	defer stop()
+
+	output, err := e.execute(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded && e.Config.Workflow != nil && e.Config.Workflow.Timeout > 0 {
+		err = fmt.Errorf("workflow timed out after %v: %w", e.Config.Workflow.Timeout, err)
+	}
+	if err != nil && !errors.Is(err, memory.ErrBudgetExceeded) {
+		if budgetErr := e.budgetExceeded(); budgetErr != nil {
+			err = budgetErr
+		}
+	}
+	return output, err
+}
+
+func (e *Executor) execute(ctx context.Context) (string, error) {
 	if e.Config.Workflow == nil {
-		return e.executeSupervisor()
+		return e.executeSupervisor(ctx)
 	}
 
 	switch e.Config.Workflow.Type {
 	case "sequential":
-		return e.executeSequential()
+		return e.executeSequential(ctx)
 	case "parallel":
-		return e.executeParallel()
+		return e.executeParallel(ctx)
+	case "collaborative":
+		return e.executeCollaborative(ctx)
+	case "dag":
+		return e.executeDAG(ctx)
 	default:
 		return "", fmt.Errorf("unknown workflow type: %s", e.Config.Workflow.Type)
 	}
 }
 
-func (e *Executor) executeSequential() (string, error) {
+func (e *Executor) executeSequential(ctx context.Context) (string, error) {
 	e.State.Start()
 
-	for _, step := range e.Config.Workflow.Steps {
-		agentDef := e.Runner.GetAgent(step.Agent)
-		if agentDef == nil {
-			err := fmt.Errorf("agent not found: %s", step.Agent)
-			e.State.Fail(err)
-			return "", err
+	for i := range e.Config.Workflow.Steps {
+		step := &e.Config.Workflow.Steps[i]
+		key := sequentialKey(i, step.NodeID())
+		if e.isCompleted(key) {
+			continue
 		}
 
-		_, err := e.Runner.RunAgent(agentDef)
-		if err != nil {
+		if err := e.runStep(ctx, step, 0); err != nil {
 			e.State.Fail(err)
 			return "", err
 		}
 
 		e.State.NextStep()
+		e.markCompleted(key)
 	}
 
 	e.State.Complete()
 	return e.Runner.GetFinalOutput(), nil
 }
 
-func (e *Executor) executeParallel() (string, error) {
+func (e *Executor) executeParallel(ctx context.Context) (string, error) {
 	e.State.Start()
 
 	// Check if any agent has listens_to defined (collaborative mode)
@@ -157,6 +212,10 @@ func (e *Executor) executeParallel() (string, error) {
 		go func(id string) {
 			defer wg.Done()
 
+			if e.isCompleted(id) {
+				return
+			}
+
 			agentDef := e.Runner.GetAgent(id)
 			if agentDef == nil {
 				mu.Lock()
@@ -176,9 +235,9 @@ func (e *Executor) executeParallel() (string, error) {
 				if agentDef.MaxTurns <= 0 {
 					agentDef.MaxTurns = maxTurns
 				}
-				response, err = e.Runner.RunCollaborativeAgent(agentDef, channel)
+				response, err = e.Runner.RunCollaborativeAgent(ctx, agentDef, channel)
 			} else {
-				response, err = e.Runner.RunAgent(agentDef)
+				response, err = e.Runner.RunAgent(ctx, agentDef)
 			}
 
 			mu.Lock()
@@ -194,6 +253,10 @@ func (e *Executor) executeParallel() (string, error) {
 				results[id] = response
 			}
 			mu.Unlock()
+
+			if err == nil {
+				e.markCompleted(id)
+			}
 		}(branchID)
 	}
 
@@ -204,7 +267,7 @@ func (e *Executor) executeParallel() (string, error) {
 		return "", firstErr
 	}
 
-	if e.Config.Workflow.Then != nil {
+	if e.Config.Workflow.Then != nil && !e.isCompleted(checkpointThen) {
 		thenAgent := e.Runner.GetAgent(e.Config.Workflow.Then.Agent)
 		if thenAgent == nil {
 			err := fmt.Errorf("then agent not found: %s", e.Config.Workflow.Then.Agent)
@@ -212,18 +275,18 @@ func (e *Executor) executeParallel() (string, error) {
 			return "", err
 		}
 
-		_, err := e.Runner.RunAgent(thenAgent)
-		if err != nil {
+		if err := e.runStep(ctx, e.Config.Workflow.Then, 0); err != nil {
 			e.State.Fail(err)
 			return "", err
 		}
+		e.markCompleted(checkpointThen)
 	}
 
 	e.State.Complete()
 	return e.Runner.GetFinalOutput(), nil
 }
 
-func (e *Executor) executeSupervisor() (string, error) {
+func (e *Executor) executeSupervisor(ctx context.Context) (string, error) {
 	e.State.Start()
 
 	var rootAgent *types.Agent
@@ -244,16 +307,35 @@ func (e *Executor) executeSupervisor() (string, error) {
 		return "", err
 	}
 
-	response, err := e.Runner.RunAgent(rootAgent)
+	if e.isCompleted(checkpointSupervisor) {
+		e.State.Complete()
+		return e.Runner.GetFinalOutput(), nil
+	}
+
+	var response string
+	var err error
+	if rootAgent.IsSupervisor() {
+		response, err = e.Runner.RunSupervisor(ctx, rootAgent)
+	} else {
+		response, err = e.Runner.RunAgent(ctx, rootAgent)
+	}
 	if err != nil {
 		e.State.Fail(err)
 		return "", err
 	}
+	e.markCompleted(checkpointSupervisor)
 
 	e.State.Complete()
 	return response, nil
 }
 
+// Close shuts down the MCP servers started for this workflow
+func (e *Executor) Close() {
+	if e.MCPClient != nil {
+		e.MCPClient.Close()
+	}
+}
+
 func (e *Executor) GetState() *State {
 	return e.State
 }
@@ -0,0 +1,421 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+// stubClient is a fake LLM that echoes the agent goal (the first line of the
+// conversation after the system message) and tracks how many calls are in
+// flight.
+type stubClient struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	active    int
+	maxActive int
+	prompts   map[string]string
+	respond   func(goal string) string // Optional custom reply
+	fail      func(goal string) error  // Optional error to return instead
+}
+
+func newStubClient(delay time.Duration) *stubClient {
+	return &stubClient{delay: delay, prompts: make(map[string]string)}
+}
+
+// Chat drops the system message so replies are keyed by the agent's goal
+func (s *stubClient) Chat(ctx context.Context, messages []types.ChatMessage) (types.ChatMessage, error) {
+	var parts []string
+	for _, msg := range messages {
+		if msg.Role != types.RoleSystem {
+			parts = append(parts, msg.Content)
+		}
+	}
+	text, err := s.Generate(ctx, strings.Join(parts, "\n\n"))
+	return types.ChatMessage{Role: types.RoleAssistant, Content: text, Usage: &types.Usage{InputTokens: 10, OutputTokens: 5}}, err
+}
+
+func (s *stubClient) Generate(ctx context.Context, prompt string) (string, error) {
+	goal := strings.SplitN(prompt, "\n", 2)[0]
+
+	s.mu.Lock()
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.prompts[goal] = prompt
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+
+	if s.fail != nil {
+		if err := s.fail(goal); err != nil {
+			return "", err
+		}
+	}
+	if s.respond != nil {
+		return s.respond(goal), nil
+	}
+	return "done:" + goal, nil
+}
+
+func newTestExecutor(config *types.WorkflowConfig, client *stubClient) *Executor {
+	config.Models = map[string]types.Model{"stub": {Provider: "ollama", Model: "stub"}}
+	for i := range config.Agents {
+		config.Agents[i].Model = "stub"
+		if config.Agents[i].Goal == "" {
+			config.Agents[i].Goal = config.Agents[i].ID
+		}
+	}
+
+	executor := NewExecutor(config)
+	executor.Runner.Clients["stub"] = client
+	return executor
+}
+
+func TestExecuteDAG_Ordering(t *testing.T) {
+	client := newStubClient(20 * time.Millisecond)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "dag",
+			Steps: []types.Step{
+				{Agent: "a"},
+				{Agent: "b"},
+				{Agent: "c", DependsOn: []string{"a"}},
+				{Agent: "d", DependsOn: []string{"b", "c"}},
+			},
+		},
+	}, client)
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:d" {
+		t.Errorf("expected final output from d, got %q", output)
+	}
+
+	// d must see the outputs of both of its dependencies
+	for _, dep := range []string{"[b]:", "[c]:"} {
+		if !strings.Contains(client.prompts["d"], dep) {
+			t.Errorf("d's prompt missing context %s", dep)
+		}
+	}
+	// c only depends on a, so it must have seen a
+	if !strings.Contains(client.prompts["c"], "[a]:") {
+		t.Error("c's prompt missing context from a")
+	}
+	if client.maxActive < 2 {
+		t.Errorf("expected a and b to run concurrently, max in flight was %d", client.maxActive)
+	}
+	if executor.State.CurrentStep != 4 {
+		t.Errorf("expected 4 completed nodes, got %d", executor.State.CurrentStep)
+	}
+
+	// With several sinks the result lists them in declaration order, not in
+	// the order they happened to finish: c finishes first, b last
+	executor = newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "dag",
+			Steps: []types.Step{
+				{Agent: "a"},
+				{Agent: "c"},
+				{Agent: "b", DependsOn: []string{"a"}},
+			},
+		},
+	}, newStubClient(20*time.Millisecond))
+	output, err = executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if want := "[c]:\ndone:c\n\n[b]:\ndone:b"; output != want {
+		t.Errorf("expected the sinks' outputs in declaration order, got %q", output)
+	}
+}
+
+func TestExecuteDAG_MaxParallel(t *testing.T) {
+	client := newStubClient(20 * time.Millisecond)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}},
+		Workflow: &types.WorkflowSpec{
+			Type:        "dag",
+			MaxParallel: 1,
+			Steps: []types.Step{
+				{Agent: "a"}, {Agent: "b"}, {Agent: "c"}, {Agent: "d"},
+			},
+		},
+	}, client)
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if client.maxActive != 1 {
+		t.Errorf("expected at most 1 node in flight, got %d", client.maxActive)
+	}
+}
+
+func TestExecuteCollaborative_Moderator(t *testing.T) {
+	client := newStubClient(0)
+	client.respond = func(goal string) string {
+		switch goal {
+		case "lead":
+			return "Looks good. <DONE/>"
+		case "dev":
+			return "still working"
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "lead"}, {ID: "dev"}, {ID: "summary"}},
+		Workflow: &types.WorkflowSpec{
+			Type:          "collaborative",
+			Collaborators: []string{"lead", "dev"},
+			MaxTurns:      50,
+			Termination:   "moderator",
+			Moderator:     "lead",
+			Then:          &types.Step{Agent: "summary"},
+		},
+	}, client)
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:summary" {
+		t.Errorf("expected then agent output, got %q", output)
+	}
+	if !strings.Contains(client.prompts["summary"], "[lead]:") {
+		t.Error("then agent should see the collaborators' output")
+	}
+}
+
+func TestExecuteCollaborative_TurnBudget(t *testing.T) {
+	client := newStubClient(0)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}, {ID: "b"}},
+		Workflow: &types.WorkflowSpec{
+			Type:          "collaborative",
+			Collaborators: []string{"a", "b"},
+			MaxTurns:      3,
+		},
+	}, client)
+
+	var mu sync.Mutex
+	turns := 0
+	executor.SetMessageCallback(func(msg memory.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		turns += strings.Count(msg.Content, "still")
+	})
+	client.respond = func(goal string) string { return "still working" }
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if turns != 3 {
+		t.Errorf("expected 3 turns across all agents, got %d", turns)
+	}
+}
+
+func TestExecuteSequential_SwitchAndWhen(t *testing.T) {
+	client := newStubClient(0)
+	client.respond = func(goal string) string {
+		if goal == "reviewer" {
+			return `Needs work. {"route": "reject"}`
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "writer"}, {ID: "reviewer"}, {ID: "fixer"}, {ID: "publisher"}, {ID: "notifier", Outputs: []string{"notice"}},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Agent: "writer"},
+				{Switch: &types.SwitchSpec{
+					Agent: "reviewer",
+					Cases: map[string][]types.Step{
+						"approve": {{Agent: "publisher"}},
+						"reject":  {{Agent: "fixer"}},
+					},
+				}},
+				{Agent: "notifier", When: `outputs.fixer startsWith "done"`},
+				{Agent: "publisher", When: `memory.notice == "never"`},
+			},
+		},
+	}, client)
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:notifier" {
+		t.Errorf("expected notifier to run last, got %q", output)
+	}
+	if _, ran := client.prompts["publisher"]; ran {
+		t.Error("publisher should not run on the reject route or when its condition is false")
+	}
+	if _, ran := client.prompts["fixer"]; !ran {
+		t.Error("fixer should run on the reject route")
+	}
+}
+
+func TestExecuteSequential_Loop(t *testing.T) {
+	client := newStubClient(0)
+	var mu sync.Mutex
+	critiques := 0
+	client.respond = func(goal string) string {
+		if goal != "critic" {
+			return "done:" + goal
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		critiques++
+		if critiques == 2 {
+			return "approved"
+		}
+		return "needs work"
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "writer"}, {ID: "critic", Outputs: []string{"verdict"}}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Loop: &types.LoopSpec{
+					Steps:         []types.Step{{Agent: "writer"}, {Agent: "critic"}},
+					Until:         `memory.verdict == "approved"`,
+					MaxIterations: 5,
+				}},
+			},
+		},
+	}, client)
+
+	var messages []memory.Message
+	executor.SetMessageCallback(func(msg memory.Message) {
+		messages = append(messages, msg)
+	})
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	if len(messages) != 4 {
+		t.Fatalf("expected 2 iterations of 2 agents, got %d messages", len(messages))
+	}
+	for i, want := range []int{1, 1, 2, 2} {
+		if messages[i].Iteration != want {
+			t.Errorf("message %d: expected iteration %d, got %d", i, want, messages[i].Iteration)
+		}
+	}
+	// The writer's second draft must see the critic's first review
+	if !strings.Contains(client.prompts["writer"], "[critic] (iteration 1):") {
+		t.Error("writer should see the previous iteration's critique")
+	}
+}
+
+func TestExecute_RecordsUsage(t *testing.T) {
+	client := newStubClient(0)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "writer", Role: "Writer"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Loop: &types.LoopSpec{Steps: []types.Step{{Agent: "writer"}}, MaxIterations: 2}},
+			},
+		},
+	}, client)
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	stats := executor.Stats.Snapshot()
+	if stats.InputTokens != 20 || stats.OutputTokens != 10 {
+		t.Errorf("expected 20/10 tokens in total, got %d/%d", stats.InputTokens, stats.OutputTokens)
+	}
+	if len(stats.Agents) != 1 {
+		t.Fatalf("expected stats for 1 agent, got %+v", stats.Agents)
+	}
+	writer := stats.Agents[0]
+	if writer.Runs != 2 || len(writer.Calls) != 2 || writer.Model != "stub" || writer.Role != "Writer" {
+		t.Errorf("expected 2 runs and calls of the stub model, got %+v", writer)
+	}
+}
+
+func TestExecute_BudgetExceeded(t *testing.T) {
+	client := newStubClient(0)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "writer"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Loop: &types.LoopSpec{Steps: []types.Step{{Agent: "writer"}}, MaxIterations: 5}},
+			},
+		},
+		Budget: &types.BudgetConfig{MaxTokens: 30},
+	}, client)
+
+	_, err := executor.Execute(context.Background())
+	if !errors.Is(err, memory.ErrBudgetExceeded) {
+		t.Fatalf("expected a budget error, got %v", err)
+	}
+	if !executor.SharedMemory.IsAborted() {
+		t.Error("expected shared memory to be aborted")
+	}
+	// Each call uses 15 tokens, so the third is refused and not retried
+	if calls := executor.Stats.Snapshot().Agents[0].Calls; len(calls) != 2 {
+		t.Errorf("expected 2 calls before the limit, got %d", len(calls))
+	}
+}
+
+func TestExecuteSequential_LoopMaxIterations(t *testing.T) {
+	client := newStubClient(0)
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "writer"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Loop: &types.LoopSpec{
+					Steps:         []types.Step{{Agent: "writer"}},
+					Until:         `iteration > 10`,
+					MaxIterations: 3,
+				}},
+			},
+		},
+	}, client)
+
+	runs := 0
+	executor.SetMessageCallback(func(msg memory.Message) { runs++ })
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("expected loop to stop after 3 iterations, ran %d", runs)
+	}
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"Orkflow/pkg/types"
+)
+
+// runForEach runs the foreach agent once per item, at most MaxParallel at a
+// time, stores the results in shared memory keyed by item, and then runs the
+// optional then step.
+func (e *Executor) runForEach(ctx context.Context, step *types.Step, iteration int) error {
+	fe := step.ForEach
+
+	template := e.Runner.GetAgent(fe.Agent)
+	if template == nil {
+		return fmt.Errorf("agent not found: %s", fe.Agent)
+	}
+
+	value, ok := e.SharedMemory.Get(fe.Items)
+	if !ok {
+		return fmt.Errorf("foreach %s: key '%s' not found in shared memory", step.NodeID(), fe.Items)
+	}
+	items := splitItems(value)
+
+	fmt.Printf("🔀 [%s] Fanning out over %d items from '%s'\n", step.NodeID(), len(items), fe.Items)
+	if e.Logger != nil {
+		e.Logger.LogAgent(step.NodeID(), "FOREACH_START", fmt.Sprintf("Items: %d, Key: %s", len(items), fe.Items))
+	}
+
+	limit := fe.MaxParallel
+	if limit <= 0 || limit > len(items) {
+		limit = len(items)
+	}
+	sem := make(chan struct{}, max(limit, 1))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	responses := make([]string, len(items))
+
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mu.Lock()
+			aborted := firstErr != nil
+			mu.Unlock()
+			if aborted {
+				return
+			}
+
+			agentDef := template.WithInstruction(fmt.Sprintf("## Item %d of %d\n\n%s", i+1, len(items), item))
+			agentDef.ID = fmt.Sprintf("%s[%d]", fe.Agent, i)
+			agentDef.Outputs = nil // Results are collected below, not published per item
+
+			response, err := e.Runner.RunAgentIteration(ctx, agentDef, iteration)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					// Abort shared memory to wake up waiting agents
+					e.SharedMemory.Abort(err.Error())
+				}
+				return
+			}
+			responses[i] = response
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return fmt.Errorf("foreach %s: %w", step.NodeID(), firstErr)
+	}
+
+	// Key results by item text, disambiguating duplicate items by position
+	results := make(map[string]interface{}, len(items))
+	for i, item := range items {
+		key := item
+		if _, dup := results[key]; dup {
+			key = fmt.Sprintf("%s#%d", item, i)
+		}
+		results[key] = responses[i]
+	}
+	e.SharedMemory.Set(fe.ResultsKey(), results)
+
+	fmt.Printf("🔀 [%s] 📤 Collected %d results into '%s'\n", step.NodeID(), len(results), fe.ResultsKey())
+	if e.Logger != nil {
+		e.Logger.LogAgent(step.NodeID(), "FOREACH_DONE", fmt.Sprintf("Results: %s", fe.ResultsKey()))
+	}
+
+	if fe.Then != nil {
+		return e.runStep(ctx, fe.Then, iteration)
+	}
+	return nil
+}
+
+// splitItems turns a shared memory value into a list of items. Strings are
+// parsed as a JSON array when possible (optionally inside a ```json fence),
+// otherwise as one item per non-empty line with list bullets removed.
+func splitItems(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		return stringifyItems(v)
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for key := range v {
+			items = append(items, key)
+		}
+		sort.Strings(items)
+		return items
+	}
+
+	text := strings.TrimSpace(fmt.Sprintf("%v", value))
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	if strings.HasPrefix(text, "[") {
+		var arr []interface{}
+		if err := json.Unmarshal([]byte(text), &arr); err == nil {
+			return stringifyItems(arr)
+		}
+	}
+
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "- ")
+		line = strings.TrimPrefix(line, "* ")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
+
+// stringifyItems converts JSON array elements to strings, re-encoding
+// anything that is not already a string
+func stringifyItems(values []interface{}) []string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			items = append(items, s)
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			items = append(items, fmt.Sprintf("%v", v))
+			continue
+		}
+		items = append(items, string(data))
+	}
+	return items
+}
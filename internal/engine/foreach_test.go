@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+func TestSplitItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{"json array", `["a.go", "b.go"]`, []string{"a.go", "b.go"}},
+		{"fenced json", "```json\n[\"x\", \"y\"]\n```", []string{"x", "y"}},
+		{"json objects", `[{"file": "a.go"}, 2]`, []string{`{"file":"a.go"}`, "2"}},
+		{"newline list", "solar\n\n- wind\n* hydro\n", []string{"solar", "wind", "hydro"}},
+		{"slice", []interface{}{"one", 2}, []string{"one", "2"}},
+		{"not json", "[draft] notes", []string{"[draft] notes"}},
+	}
+
+	for _, tt := range tests {
+		got := splitItems(tt.value)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: splitItems() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteSequential_ForEach(t *testing.T) {
+	client := newStubClient(20 * time.Millisecond)
+	client.respond = func(goal string) string {
+		if goal == "planner" {
+			return `["solar", "wind", "hydro", "geothermal"]`
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "planner", Outputs: []string{"topics"}},
+			{ID: "researcher"},
+			{ID: "editor"},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Agent: "planner"},
+				{ForEach: &types.ForEachSpec{
+					Items:       "topics",
+					Agent:       "researcher",
+					MaxParallel: 2,
+					Output:      "research",
+					Then:        &types.Step{Agent: "editor"},
+				}},
+			},
+		},
+	}, client)
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:editor" {
+		t.Errorf("expected then agent output, got %q", output)
+	}
+	if client.maxActive > 2 {
+		t.Errorf("expected at most 2 items in flight, got %d", client.maxActive)
+	}
+
+	val, ok := executor.SharedMemory.Get("research")
+	if !ok {
+		t.Fatal("expected collected results in shared memory")
+	}
+	results := val.(map[string]interface{})
+	if len(results) != 4 || results["wind"] != "done:researcher" {
+		t.Errorf("unexpected results: %v", results)
+	}
+
+	// The then agent sees every item's output in its context
+	for i := 0; i < 4; i++ {
+		if !strings.Contains(client.prompts["editor"], "[researcher["+string(rune('0'+i))+"]]:") {
+			t.Errorf("editor prompt missing output of item %d", i)
+		}
+	}
+}
+
+func TestExecuteSequential_ForEachItemsAreNotTemplates(t *testing.T) {
+	client := newStubClient(0)
+	client.respond = func(goal string) string {
+		if goal == "planner" {
+			return `["check {{ memory.a.b }} and {{ memory }}"]`
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "planner", Outputs: []string{"topics"}},
+			{ID: "researcher"},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Agent: "planner"},
+				{ForEach: &types.ForEachSpec{Items: "topics", Agent: "researcher"}},
+			},
+		},
+	}, client)
+
+	if _, err := executor.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(client.prompts["researcher"], "check {{ memory.a.b }} and {{ memory }}") {
+		t.Errorf("expected the item to reach the prompt as written, got %q", client.prompts["researcher"])
+	}
+}
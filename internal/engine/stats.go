@@ -1,8 +1,13 @@
 package engine
 
 import (
+	"sort"
 	"sync"
 	"time"
+
+	"Orkflow/internal/memory"
+	"Orkflow/internal/pricing"
+	"Orkflow/pkg/types"
 )
 
 // ExecutionStats tracks timing and cost for a workflow run
@@ -10,59 +15,109 @@ type ExecutionStats struct {
 	mu          sync.Mutex
 	StartTime   time.Time
 	AgentStats  map[string]*AgentStat
+	Pricing     *pricing.Table // Rates used by EstimateCost
+	TotalCalls  int
 	TotalTokens struct {
 		Input  int
 		Output int
 	}
 }
 
-// AgentStat tracks per-agent statistics
+// AgentStat tracks per-agent statistics. Agents that run more than once,
+// in loops, foreach steps or delegations, accumulate across runs.
 type AgentStat struct {
-	AgentID      string
-	Role         string
-	Model        string
-	StartTime    time.Time
-	Duration     time.Duration
-	InputTokens  int
-	OutputTokens int
-	Completed    bool
+	AgentID           string
+	Role              string
+	Provider          string
+	Model             string
+	StartTime         time.Time
+	Duration          time.Duration
+	Runs              int
+	InputTokens       int
+	CachedInputTokens int
+	OutputTokens      int
+	Calls             []CallStat
+	Completed         bool
+}
+
+// CallStat tracks a single model call
+type CallStat struct {
+	Provider          string
+	Model             string
+	InputTokens       int
+	CachedInputTokens int
+	OutputTokens      int
+	Duration          time.Duration
 }
 
-// NewExecutionStats creates a new stats tracker
+// NewExecutionStats creates a new stats tracker priced with the built-in
+// table
 func NewExecutionStats() *ExecutionStats {
 	return &ExecutionStats{
 		StartTime:  time.Now(),
 		AgentStats: make(map[string]*AgentStat),
+		Pricing:    pricing.Default(),
 	}
 }
 
 // StartAgent marks an agent as started
-func (s *ExecutionStats) StartAgent(agentID, role, model string) {
+func (s *ExecutionStats) StartAgent(agentID, role, provider, model string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.AgentStats[agentID] = &AgentStat{
-		AgentID:   agentID,
-		Role:      role,
-		Model:     model,
-		StartTime: time.Now(),
-	}
+	stat := s.agent(agentID)
+	stat.Role = role
+	stat.Provider = provider
+	stat.Model = model
+	stat.StartTime = time.Now()
+	stat.Runs++
 }
 
-// CompleteAgent marks an agent as completed with token counts
-func (s *ExecutionStats) CompleteAgent(agentID string, inputTokens, outputTokens int) {
+// RecordCall adds the token usage of one model call made by an agent. The
+// model that served it becomes the agent's model, so a fallback shows.
+func (s *ExecutionStats) RecordCall(agentID, provider, model string, usage types.Usage, duration time.Duration) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if stat, ok := s.AgentStats[agentID]; ok {
-		stat.Duration = time.Since(stat.StartTime)
-		stat.InputTokens = inputTokens
-		stat.OutputTokens = outputTokens
-		stat.Completed = true
+	stat := s.agent(agentID)
+	stat.Provider = provider
+	stat.Model = model
+	stat.Calls = append(stat.Calls, CallStat{
+		Provider:          provider,
+		Model:             model,
+		InputTokens:       usage.InputTokens,
+		CachedInputTokens: usage.CachedInputTokens,
+		OutputTokens:      usage.OutputTokens,
+		Duration:          duration,
+	})
+	stat.InputTokens += usage.InputTokens
+	stat.CachedInputTokens += usage.CachedInputTokens
+	stat.OutputTokens += usage.OutputTokens
+
+	s.TotalCalls++
+	s.TotalTokens.Input += usage.InputTokens
+	s.TotalTokens.Output += usage.OutputTokens
+}
 
-		s.TotalTokens.Input += inputTokens
-		s.TotalTokens.Output += outputTokens
+// CompleteAgent marks an agent run as completed
+func (s *ExecutionStats) CompleteAgent(agentID string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat := s.agent(agentID)
+	stat.Duration += duration
+	stat.Completed = true
+}
+
+// agent returns the stats of an agent, creating them on first use. Callers
+// hold mu.
+func (s *ExecutionStats) agent(agentID string) *AgentStat {
+	stat, ok := s.AgentStats[agentID]
+	if !ok {
+		stat = &AgentStat{AgentID: agentID, StartTime: time.Now()}
+		s.AgentStats[agentID] = stat
 	}
+	return stat
 }
 
 // GetElapsedTime returns total elapsed time
@@ -88,25 +143,97 @@ func (s *ExecutionStats) GetCompletedCount() int {
 func (s *ExecutionStats) EstimateCost() float64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.estimateCost()
+}
 
-	// Pricing per 1M tokens (input/output)
-	pricing := map[string]struct{ Input, Output float64 }{
-		"gpt-4o":           {2.50, 10.00},
-		"gpt-4o-mini":      {0.15, 0.60},
-		"gpt-4-turbo":      {10.00, 30.00},
-		"gpt-3.5-turbo":    {0.50, 1.50},
-		"gemini-2.0-flash": {0.075, 0.30},
-		"gemini-1.5-pro":   {1.25, 5.00},
-	}
+// Totals returns the tokens, calls and estimated cost of the run so far
+func (s *ExecutionStats) Totals() (tokens, calls int, cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.TotalTokens.Input + s.TotalTokens.Output, s.TotalCalls, s.estimateCost()
+}
 
+// estimateCost prices the usage so far, each call at the rate of the model
+// that served it. Unpriced models count as free. Callers hold mu.
+func (s *ExecutionStats) estimateCost() float64 {
 	var totalCost float64
 	for _, stat := range s.AgentStats {
-		if p, ok := pricing[stat.Model]; ok {
-			inputCost := float64(stat.InputTokens) / 1000000 * p.Input
-			outputCost := float64(stat.OutputTokens) / 1000000 * p.Output
-			totalCost += inputCost + outputCost
+		for _, call := range stat.Calls {
+			cost, _ := s.Pricing.Cost(call.Provider, call.Model, types.Usage{
+				InputTokens:       call.InputTokens,
+				CachedInputTokens: call.CachedInputTokens,
+				OutputTokens:      call.OutputTokens,
+			})
+			totalCost += cost
 		}
 	}
-
 	return totalCost
 }
+
+// Snapshot returns the stats in the form saved with a session, with agents
+// sorted by ID
+func (s *ExecutionStats) Snapshot() *memory.RunStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &memory.RunStats{
+		Duration:      time.Since(s.StartTime),
+		InputTokens:   s.TotalTokens.Input,
+		OutputTokens:  s.TotalTokens.Output,
+		EstimatedCost: s.estimateCost(),
+	}
+	for _, stat := range s.AgentStats {
+		agent := memory.AgentStats{
+			AgentID:           stat.AgentID,
+			Role:              stat.Role,
+			Provider:          stat.Provider,
+			Model:             stat.Model,
+			Runs:              stat.Runs,
+			Duration:          stat.Duration,
+			InputTokens:       stat.InputTokens,
+			CachedInputTokens: stat.CachedInputTokens,
+			OutputTokens:      stat.OutputTokens,
+		}
+		for _, call := range stat.Calls {
+			agent.Calls = append(agent.Calls, memory.CallStats(call))
+		}
+		stats.Agents = append(stats.Agents, agent)
+	}
+	sort.Slice(stats.Agents, func(i, j int) bool {
+		return stats.Agents[i].AgentID < stats.Agents[j].AgentID
+	})
+	return stats
+}
+
+// Restore seeds the stats with the usage of an earlier run of the same
+// workflow. The elapsed time continues from the earlier run's duration.
+func (s *ExecutionStats) Restore(stats *memory.RunStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.StartTime = time.Now().Add(-stats.Duration)
+	s.AgentStats = make(map[string]*AgentStat, len(stats.Agents))
+	s.TotalCalls = 0
+	s.TotalTokens.Input = stats.InputTokens
+	s.TotalTokens.Output = stats.OutputTokens
+	for _, agent := range stats.Agents {
+		stat := &AgentStat{
+			AgentID:           agent.AgentID,
+			Role:              agent.Role,
+			Provider:          agent.Provider,
+			Model:             agent.Model,
+			StartTime:         s.StartTime,
+			Duration:          agent.Duration,
+			Runs:              agent.Runs,
+			InputTokens:       agent.InputTokens,
+			CachedInputTokens: agent.CachedInputTokens,
+			OutputTokens:      agent.OutputTokens,
+			Completed:         agent.Duration > 0, // Duration only grows when a run completes
+		}
+		for _, call := range agent.Calls {
+			stat.Calls = append(stat.Calls, CallStat(call))
+		}
+		s.TotalCalls += len(agent.Calls)
+		s.AgentStats[agent.AgentID] = stat
+	}
+}
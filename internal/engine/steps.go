@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"Orkflow/internal/agent"
+	"Orkflow/pkg/types"
+)
+
+// DefaultMaxIterations caps loops that do not set max_iterations
+const DefaultMaxIterations = 5
+
+// runSteps runs steps in order, stopping at the first error. iteration is
+// the enclosing loop iteration (0 outside loops).
+func (e *Executor) runSteps(ctx context.Context, steps []types.Step, iteration int) error {
+	for i := range steps {
+		if err := e.runStep(ctx, &steps[i], iteration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep runs a single step: it checks the when condition, then runs a
+// loop or foreach, routes through a switch, runs a sub-workflow, or runs the
+// step's agent.
+func (e *Executor) runStep(ctx context.Context, step *types.Step, iteration int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if step.When != "" {
+		ok, err := e.evalCondition(step.When, nil)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.NodeID(), err)
+		}
+		if !ok {
+			fmt.Printf("⏭️  Skipping %s (when: %s)\n", step.NodeID(), step.When)
+			if e.Logger != nil {
+				e.Logger.LogAgent(step.NodeID(), "SKIPPED", fmt.Sprintf("when: %s", step.When))
+			}
+			return nil
+		}
+	}
+
+	if step.Loop != nil {
+		return e.runLoop(ctx, step)
+	}
+	if step.ForEach != nil {
+		return e.runForEach(ctx, step, iteration)
+	}
+	if step.Switch != nil {
+		return e.runSwitch(ctx, step.Switch, iteration)
+	}
+	if step.Workflow != "" {
+		return e.runSubWorkflow(ctx, step, iteration)
+	}
+
+	agentDef := e.Runner.GetAgent(step.Agent)
+	if agentDef == nil {
+		return fmt.Errorf("agent not found: %s", step.Agent)
+	}
+
+	_, err := e.Runner.RunAgentIteration(ctx, agentDef, iteration)
+	return err
+}
+
+// runSwitch asks the router agent to pick a route and runs that case's steps
+func (e *Executor) runSwitch(ctx context.Context, sw *types.SwitchSpec, iteration int) error {
+	router := e.Runner.GetAgent(sw.Agent)
+	if router == nil {
+		return fmt.Errorf("router agent not found: %s", sw.Agent)
+	}
+
+	routes := make([]string, 0, len(sw.Cases))
+	for route := range sw.Cases {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	instruction := fmt.Sprintf(`## Routing
+
+Decide which route the workflow should take next. Valid routes: %s.
+End your reply with a JSON object naming exactly one route, for example:
+{"route": "%s"}`, strings.Join(routes, ", "), routes[0])
+
+	response, err := e.Runner.RunAgentIteration(ctx, router.WithInstruction(instruction), iteration)
+	if err != nil {
+		return err
+	}
+
+	route, ok := agent.ParseRoute(response, routes)
+	if !ok {
+		if sw.Default == nil {
+			return fmt.Errorf("router %s chose no valid route (expected one of: %s)", sw.Agent, strings.Join(routes, ", "))
+		}
+		fmt.Printf("🔀 [%s] No valid route chosen, taking default\n", sw.Agent)
+		if e.Logger != nil {
+			e.Logger.LogAgent(sw.Agent, "ROUTE", "default")
+		}
+		return e.runSteps(ctx, sw.Default, iteration)
+	}
+
+	fmt.Printf("🔀 [%s] Route: %s\n", sw.Agent, route)
+	if e.Logger != nil {
+		e.Logger.LogAgent(sw.Agent, "ROUTE", route)
+	}
+	return e.runSteps(ctx, sw.Cases[route], iteration)
+}
+
+// runLoop repeats the loop body until its until expression holds or
+// max_iterations is reached. Outputs are tagged with the 1-based iteration.
+func (e *Executor) runLoop(ctx context.Context, step *types.Step) error {
+	loop := step.Loop
+	maxIterations := loop.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		fmt.Printf("🔁 [%s] Iteration %d/%d\n", step.NodeID(), iteration, maxIterations)
+		if e.Logger != nil {
+			e.Logger.LogAgent(step.NodeID(), "LOOP_ITERATION", fmt.Sprintf("%d/%d", iteration, maxIterations))
+		}
+
+		if err := e.runSteps(ctx, loop.Steps, iteration); err != nil {
+			return fmt.Errorf("loop %s iteration %d: %w", step.NodeID(), iteration, err)
+		}
+
+		if loop.Until == "" {
+			continue
+		}
+		done, err := e.evalCondition(loop.Until, map[string]interface{}{"iteration": iteration})
+		if err != nil {
+			return fmt.Errorf("loop %s: %w", step.NodeID(), err)
+		}
+		if done {
+			fmt.Printf("🔁 [%s] Exit condition met after %d iterations\n", step.NodeID(), iteration)
+			if e.Logger != nil {
+				e.Logger.LogAgent(step.NodeID(), "LOOP_DONE", fmt.Sprintf("until: %s (iteration %d)", loop.Until, iteration))
+			}
+			return nil
+		}
+	}
+
+	fmt.Printf("🔁 [%s] Stopped after max_iterations (%d)\n", step.NodeID(), maxIterations)
+	if e.Logger != nil {
+		e.Logger.LogAgent(step.NodeID(), "LOOP_MAX_ITERATIONS", fmt.Sprintf("%d", maxIterations))
+	}
+	return nil
+}
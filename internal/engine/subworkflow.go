@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+// runSubWorkflow runs a referenced workflow in a child executor with its own
+// shared memory. Mapped inputs are copied in before the run and mapped
+// outputs are copied back out afterwards. The child's stats, logger and
+// session messages roll up into this executor.
+func (e *Executor) runSubWorkflow(ctx context.Context, step *types.Step, iteration int) error {
+	nodeID := step.NodeID()
+	if step.SubWorkflow == nil {
+		return fmt.Errorf("workflow %s was not resolved", step.Workflow)
+	}
+
+	child := e.newChildExecutor(nodeID, step.SubWorkflow)
+	defer child.Close() // Stops the child's MCP servers after every run of the step
+
+	for childKey, parentKey := range step.Inputs {
+		value, ok := e.SharedMemory.Get(parentKey)
+		if !ok {
+			return fmt.Errorf("workflow %s: input key '%s' not found in shared memory", nodeID, parentKey)
+		}
+		child.SharedMemory.Set(childKey, value)
+	}
+
+	fmt.Printf("📂 [%s] Running sub-workflow %s\n", nodeID, step.Workflow)
+	if e.Logger != nil {
+		e.Logger.LogAgent(nodeID, "SUBWORKFLOW_START", step.Workflow)
+	}
+
+	output, err := child.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("workflow %s: %w", nodeID, err)
+	}
+
+	for parentKey, childKey := range step.Outputs {
+		value, ok := child.SharedMemory.Get(childKey)
+		if !ok {
+			return fmt.Errorf("workflow %s: output key '%s' was not produced", nodeID, childKey)
+		}
+		e.SharedMemory.Set(parentKey, value)
+	}
+
+	// The child's final output is visible to later steps like any agent output
+	e.Runner.Context.AddIterationOutput(nodeID, output, iteration)
+
+	fmt.Printf("📂 [%s] ✅ Sub-workflow finished\n", nodeID)
+	if e.Logger != nil {
+		e.Logger.LogAgent(nodeID, "SUBWORKFLOW_DONE", step.Workflow)
+	}
+	return nil
+}
+
+// newChildExecutor builds the executor for a sub-workflow. Models without an
+// API key use the parent's key for the same provider, clients for models
+// configured identically in the parent are reused, and messages are
+// attributed as "<node>/<agent>" in the parent's session. A sub-workflow
+// without its own file_access policy is held to the parent's.
+func (e *Executor) newChildExecutor(nodeID string, config *types.WorkflowConfig) *Executor {
+	inherited := *config
+	inherited.Models = types.InheritAPIKeys(config.Models, e.Config.Models)
+	if inherited.FileAccess == nil {
+		inherited.FileAccess = e.Config.FileAccess
+	}
+	config = &inherited
+	child := NewExecutor(config)
+	child.Stats = e.Stats
+	child.Runner.Stats = prefixedStats{prefix: nodeID + "/", stats: e.Stats}
+	child.Runner.CallGuard = func(agentID string) error {
+		// Both budgets apply, and both count the whole run's usage
+		if err := e.Runner.CallGuard(nodeID + "/" + agentID); err != nil {
+			return err
+		}
+		return child.checkBudget(agentID)
+	}
+
+	for name, model := range config.Models {
+		if parentModel, ok := e.Config.Models[name]; ok && sameClient(parentModel, model) {
+			if client, ok := e.Runner.Clients[name]; ok {
+				child.Runner.Clients[name] = client
+			}
+		}
+	}
+
+	if e.Logger != nil {
+		child.SetLogger(e.Logger)
+	}
+	if callback := e.Runner.MessageCallback; callback != nil {
+		child.SetMessageCallback(func(msg memory.Message) {
+			msg.AgentID = nodeID + "/" + msg.AgentID
+			callback(msg)
+		})
+	}
+	if callback := e.Runner.TokenCallback; callback != nil {
+		child.SetTokenCallback(func(agentID, token string) {
+			callback(nodeID+"/"+agentID, token)
+		})
+	}
+	child.Runner.SessionHistory = e.Runner.SessionHistory
+	return child
+}
+
+// sameClient reports whether two model entries build identical clients
+func sameClient(a, b types.Model) bool {
+	return a.Provider == b.Provider && a.Model == b.Model && a.Endpoint == b.Endpoint && a.APIKey == b.APIKey
+}
+
+// prefixedStats records a sub-workflow's agents in the parent's stats as
+// "<node>/<agent>", matching how their messages are attributed
+type prefixedStats struct {
+	prefix string
+	stats  *ExecutionStats
+}
+
+func (p prefixedStats) StartAgent(agentID, role, provider, model string) {
+	p.stats.StartAgent(p.prefix+agentID, role, provider, model)
+}
+
+func (p prefixedStats) RecordCall(agentID, provider, model string, usage types.Usage, duration time.Duration) {
+	p.stats.RecordCall(p.prefix+agentID, provider, model, usage, duration)
+}
+
+func (p prefixedStats) CompleteAgent(agentID string, duration time.Duration) {
+	p.stats.CompleteAgent(p.prefix+agentID, duration)
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"Orkflow/internal/agent"
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+func TestExecuteSequential_SubWorkflow(t *testing.T) {
+	client := newStubClient(0)
+
+	child := &types.WorkflowConfig{
+		Models: map[string]types.Model{"stub": {Provider: "ollama", Model: "stub"}},
+		Agents: []types.Agent{
+			{ID: "researcher", Goal: "researcher", Model: "stub", Requires: []string{"topic"}},
+			{ID: "writer", Goal: "writer", Model: "stub", Outputs: []string{"article"}},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type:  "sequential",
+			Steps: []types.Step{{Agent: "researcher"}, {Agent: "writer"}},
+		},
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "planner", Outputs: []string{"plan"}}, {ID: "editor"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{
+				{Agent: "planner"},
+				{
+					Workflow:    "lib/research.yaml",
+					Inputs:      map[string]string{"topic": "plan"},
+					Outputs:     map[string]string{"draft": "article"},
+					SubWorkflow: child,
+				},
+				{Agent: "editor"},
+			},
+		},
+	}, client)
+
+	var mu sync.Mutex
+	var agents []string
+	executor.SetMessageCallback(func(msg memory.Message) {
+		mu.Lock()
+		agents = append(agents, msg.AgentID)
+		mu.Unlock()
+	})
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:editor" {
+		t.Errorf("expected final output from editor, got %q", output)
+	}
+
+	// The child saw the mapped input and the parent got the mapped output
+	if !strings.Contains(client.prompts["researcher"], "done:planner") {
+		t.Error("researcher did not receive the mapped topic")
+	}
+	if draft, ok := executor.SharedMemory.Get("draft"); !ok || draft != "done:writer" {
+		t.Errorf("expected draft from child writer, got %v", draft)
+	}
+	if !strings.Contains(client.prompts["editor"], "[research]:") {
+		t.Error("editor's prompt missing the sub-workflow output")
+	}
+
+	want := []string{"planner", "research/researcher", "research/writer", "editor"}
+	if strings.Join(agents, ",") != strings.Join(want, ",") {
+		t.Errorf("expected session messages %v, got %v", want, agents)
+	}
+}
+
+func TestExecuteSequential_SubWorkflowMissingInput(t *testing.T) {
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{{ID: "a"}},
+		Workflow: &types.WorkflowSpec{
+			Type: "sequential",
+			Steps: []types.Step{{
+				Workflow: "child.yaml",
+				Inputs:   map[string]string{"topic": "nope"},
+				SubWorkflow: &types.WorkflowConfig{
+					Agents:   []types.Agent{{ID: "a"}},
+					Workflow: &types.WorkflowSpec{Type: "sequential", Steps: []types.Step{{Agent: "a"}}},
+				},
+			}},
+		},
+	}, newStubClient(0))
+
+	if _, err := executor.Execute(context.Background()); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("expected missing input error, got %v", err)
+	}
+}
+
+func TestExecuteSequential_SubWorkflowInheritsAPIKey(t *testing.T) {
+	client := newStubClient(0)
+	gemini := types.Model{Provider: "google", Model: "gemini-2.5-flash"}
+	keyed := gemini
+	keyed.APIKey = "parent-key"
+
+	// The child file names the same model without a key, as when the key
+	// comes from the environment
+	child := &types.WorkflowConfig{
+		Models: map[string]types.Model{"gemini": gemini, "fast": {Provider: "google", Model: "gemini-2.5-flash-lite"}},
+		Agents: []types.Agent{{ID: "writer", Goal: "writer", Model: "gemini"}},
+		Workflow: &types.WorkflowSpec{
+			Type:  "sequential",
+			Steps: []types.Step{{Agent: "writer"}},
+		},
+	}
+	step := types.Step{Workflow: "lib/research.yaml", SubWorkflow: child}
+
+	executor := NewExecutor(&types.WorkflowConfig{
+		Models:   map[string]types.Model{"gemini": keyed},
+		Agents:   []types.Agent{{ID: "planner", Goal: "planner", Model: "gemini"}},
+		Workflow: &types.WorkflowSpec{Type: "sequential", Steps: []types.Step{{Agent: "planner"}, step}},
+	})
+	executor.Runner.Clients["gemini"] = client
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "done:writer" {
+		t.Errorf("expected the child to reuse the parent's client, got %q", output)
+	}
+
+	// Other models of the provider get the parent's key for their own client
+	sub := executor.newChildExecutor("research", child)
+	if c, ok := sub.Runner.Clients["fast"].(*agent.GeminiClient); !ok || c.APIKey != "parent-key" {
+		t.Errorf("expected the child's other model to inherit the key, got %#v", sub.Runner.Clients["fast"])
+	}
+	if child.Models["fast"].APIKey != "" {
+		t.Error("the parsed sub-workflow config was modified")
+	}
+}
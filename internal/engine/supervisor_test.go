@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
+)
+
+func TestExecuteSupervisor_Delegation(t *testing.T) {
+	client := newStubClient(0)
+	client.respond = func(goal string) string {
+		prompt := client.prompts[goal]
+		switch goal {
+		case "boss":
+			if strings.Contains(prompt, "## Delegation Results") {
+				return "final answer"
+			}
+			return `<delegate to="lead">count the files</delegate>`
+		case "lead":
+			if strings.Contains(prompt, "## Delegation Results") {
+				return "lead summary"
+			}
+			return `<delegate to="worker">check the tests</delegate>`
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "boss", SubAgents: []string{"lead"}, Outputs: []string{"answer"}},
+			{ID: "lead", SubAgents: []string{"worker"}},
+			{ID: "worker", Role: "Worker"},
+		},
+	}, client)
+	var messages []memory.Message
+	executor.SetMessageCallback(func(msg memory.Message) { messages = append(messages, msg) })
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "final answer" {
+		t.Errorf("expected supervisor's final answer, got %q", output)
+	}
+
+	if !strings.Contains(client.prompts["boss"], "- lead") {
+		t.Error("supervisor prompt does not list its sub-agents")
+	}
+	if !strings.Contains(client.prompts["boss"], "lead summary") {
+		t.Error("supervisor did not receive the delegation result")
+	}
+	if !strings.Contains(client.prompts["worker"], "check the tests") {
+		t.Error("nested supervisor did not delegate to the worker")
+	}
+	if answer, _ := executor.SharedMemory.Get("answer"); answer != "final answer" {
+		t.Errorf("expected only the final answer published, got %v", answer)
+	}
+
+	// Delegating turns are not recorded, so they don't reach later prompts
+	if strings.Contains(client.prompts["boss"], "[boss]:") || strings.Contains(client.prompts["worker"], "<delegate") {
+		t.Error("a delegating turn was recorded as history")
+	}
+	var saved []string
+	for _, msg := range messages {
+		saved = append(saved, msg.AgentID+"="+msg.Content)
+	}
+	want := "worker=done:worker,lead=lead summary,boss=final answer"
+	if strings.Join(saved, ",") != want {
+		t.Errorf("expected session messages %s, got %v", want, saved)
+	}
+}
+
+func TestExecuteSupervisor_Limits(t *testing.T) {
+	client := newStubClient(0)
+	calls := map[string]int{}
+	client.respond = func(goal string) string {
+		calls[goal]++
+		if goal == "lead" {
+			// A supervisor past max_depth must answer directly
+			return "lead answer"
+		}
+		if goal == "boss" && strings.Contains(client.prompts[goal], "No delegations remaining") {
+			return `<delegate to="lead">again</delegate> giving up`
+		}
+		if goal == "boss" {
+			return `<delegate to="lead">go</delegate>`
+		}
+		return "done:" + goal
+	}
+
+	executor := newTestExecutor(&types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "boss", SubAgents: []string{"lead"}, MaxDelegations: 2, MaxDepth: 1},
+			{ID: "lead", SubAgents: []string{"worker"}},
+			{ID: "worker"},
+		},
+	}, client)
+
+	output, err := executor.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if output != "giving up" {
+		t.Errorf("expected stripped reply after the limit, got %q", output)
+	}
+	for _, out := range executor.Runner.Context.Snapshot() {
+		if out.AgentID == "boss" && out.Response != "giving up" {
+			t.Errorf("expected only the stripped answer recorded, got %q", out.Response)
+		}
+	}
+	if calls["lead"] != 2 {
+		t.Errorf("expected 2 delegations, got %d", calls["lead"])
+	}
+	if calls["worker"] != 0 {
+		t.Errorf("max_depth 1 should stop nested delegation, worker ran %d times", calls["worker"])
+	}
+}
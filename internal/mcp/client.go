@@ -112,8 +112,9 @@ func (c *Client) GetAllTools() map[string][]*mcp.Tool {
 	return result
 }
 
-// CallTool executes a tool on an MCP server
-func (c *Client) CallTool(serverName, toolName string, args map[string]interface{}) (string, error) {
+// CallTool executes a tool on an MCP server. The call is cancelled when
+// either ctx or the client is done.
+func (c *Client) CallTool(ctx context.Context, serverName, toolName string, args map[string]interface{}) (string, error) {
 	c.mu.RLock()
 	server, ok := c.servers[serverName]
 	c.mu.RUnlock()
@@ -127,7 +128,12 @@ func (c *Client) CallTool(serverName, toolName string, args map[string]interface
 		Arguments: args,
 	}
 
-	result, err := server.session.CallTool(c.ctx, params)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	result, err := server.session.CallTool(ctx, params)
 	if err != nil {
 		return "", fmt.Errorf("tool call failed: %w", err)
 	}
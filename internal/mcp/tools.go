@@ -1,7 +1,10 @@
 package mcp
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"Orkflow/internal/tools"
 
@@ -23,17 +26,41 @@ func (t *MCPTool) Description() string {
 	return t.ToolDef.Description
 }
 
-func (t *MCPTool) Execute(input string) (string, error) {
-	// Parse input as simple key=value or just pass as single arg
-	args := map[string]interface{}{
-		"input": input,
+// InputSchema returns the schema the server advertised for the tool
+func (t *MCPTool) InputSchema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	if t.ToolDef.InputSchema != nil {
+		data, err := json.Marshal(t.ToolDef.InputSchema)
+		if err == nil {
+			_ = json.Unmarshal(data, &schema)
+		}
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	return schema
+}
+
+func (t *MCPTool) Execute(ctx context.Context, input string) (string, error) {
+	// A JSON object is passed through as the arguments, anything else as a single arg
+	var args map[string]interface{}
+	if trimmed := strings.TrimSpace(input); !strings.HasPrefix(trimmed, "{") || json.Unmarshal([]byte(trimmed), &args) != nil {
+		args = map[string]interface{}{
+			"input": input,
+		}
 	}
 
-	return t.Client.CallTool(t.ServerName, t.ToolDef.Name, args)
+	return t.Client.CallTool(ctx, t.ServerName, t.ToolDef.Name, args)
+}
+
+// ExecuteArgs calls the tool with arguments from a native tool call
+func (t *MCPTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	return t.Client.CallTool(ctx, t.ServerName, t.ToolDef.Name, args)
 }
 
-// RegisterMCPTools registers all tools from an MCP server with the tool registry
-func RegisterMCPTools(client *Client, serverName string) error {
+// RegisterMCPTools registers all tools from an MCP server with a workflow's
+// tool registry
+func RegisterMCPTools(client *Client, serverName string, registry *tools.Registry) error {
 	mcpTools, err := client.GetTools(serverName)
 	if err != nil {
 		return err
@@ -45,7 +72,7 @@ func RegisterMCPTools(client *Client, serverName string) error {
 			ToolDef:    toolDef,
 			Client:     client,
 		}
-		tools.Register(tool)
+		registry.Register(tool)
 		fmt.Printf("  📦 Registered MCP tool: %s\n", tool.Name())
 	}
 
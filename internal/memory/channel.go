@@ -18,10 +18,15 @@ type ChannelMessage struct {
 // It allows agents running in parallel to send and receive messages during execution.
 type MessageChannel struct {
 	mu          sync.RWMutex
-	messages    []ChannelMessage                 // All messages (append-only log)
-	subscribers map[string]chan ChannelMessage   // Agent ID -> their inbox channel
-	bufferSize  int                              // Size of each subscriber's channel buffer
-	closed      bool                             // Whether the channel has been closed
+	messages    []ChannelMessage               // All messages (append-only log)
+	subscribers map[string]chan ChannelMessage // Agent ID -> their inbox channel
+	bufferSize  int                            // Size of each subscriber's channel buffer
+	closed      bool                           // Whether the channel has been closed
+
+	turnBudget int                                 // Total turns shared by all agents (0 = unlimited)
+	turnsUsed  int                                 // Turns consumed so far
+	done       map[string]bool                     // Agents that have signaled DONE
+	onDone     func(agentID string, doneCount int) // Called after an agent signals DONE
 }
 
 // NewMessageChannel creates a new message channel for collaborative workflows.
@@ -35,6 +40,7 @@ func NewMessageChannel(bufferSize int) *MessageChannel {
 		subscribers: make(map[string]chan ChannelMessage),
 		bufferSize:  bufferSize,
 		closed:      false,
+		done:        make(map[string]bool),
 	}
 }
 
@@ -169,6 +175,67 @@ func (mc *MessageChannel) Close() {
 	mc.subscribers = make(map[string]chan ChannelMessage)
 }
 
+// SetTurnBudget sets the total number of turns all agents may take together.
+// A budget of 0 means unlimited.
+func (mc *MessageChannel) SetTurnBudget(turns int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.turnBudget = turns
+}
+
+// TakeTurn consumes one turn from the shared budget. It returns false when
+// the budget is exhausted or the channel is closed.
+func (mc *MessageChannel) TakeTurn() bool {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.closed {
+		return false
+	}
+	if mc.turnBudget > 0 && mc.turnsUsed >= mc.turnBudget {
+		return false
+	}
+	mc.turnsUsed++
+	return true
+}
+
+// TurnsUsed returns the number of turns consumed from the shared budget.
+func (mc *MessageChannel) TurnsUsed() int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.turnsUsed
+}
+
+// OnDone registers a callback invoked each time an agent signals DONE.
+// The callback runs without the channel lock held, so it may call Close.
+func (mc *MessageChannel) OnDone(callback func(agentID string, doneCount int)) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.onDone = callback
+}
+
+// MarkDone records that an agent has signaled DONE and returns how many
+// distinct agents are done.
+func (mc *MessageChannel) MarkDone(agentID string) int {
+	mc.mu.Lock()
+	mc.done[agentID] = true
+	count := len(mc.done)
+	callback := mc.onDone
+	mc.mu.Unlock()
+
+	if callback != nil {
+		callback(agentID, count)
+	}
+	return count
+}
+
+// IsDone returns whether an agent has signaled DONE.
+func (mc *MessageChannel) IsDone(agentID string) bool {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.done[agentID]
+}
+
 // IsClosed returns whether the channel has been closed.
 func (mc *MessageChannel) IsClosed() bool {
 	mc.mu.RLock()
@@ -294,3 +294,71 @@ func TestSubscriberCount(t *testing.T) {
 		t.Errorf("expected subscriber count 1, got %d", mc.SubscriberCount())
 	}
 }
+
+func TestTurnBudget(t *testing.T) {
+	mc := NewMessageChannel(10)
+	defer mc.Close()
+
+	mc.SetTurnBudget(3)
+	for i := 0; i < 3; i++ {
+		if !mc.TakeTurn() {
+			t.Fatalf("turn %d should be allowed", i+1)
+		}
+	}
+	if mc.TakeTurn() {
+		t.Error("turn 4 should exceed the budget")
+	}
+	if mc.TurnsUsed() != 3 {
+		t.Errorf("expected 3 turns used, got %d", mc.TurnsUsed())
+	}
+}
+
+func TestTurnBudgetUnlimited(t *testing.T) {
+	mc := NewMessageChannel(10)
+
+	for i := 0; i < 50; i++ {
+		if !mc.TakeTurn() {
+			t.Fatalf("turn %d should be allowed without a budget", i+1)
+		}
+	}
+
+	mc.Close()
+	if mc.TakeTurn() {
+		t.Error("no turns should be allowed after Close")
+	}
+}
+
+func TestMarkDone(t *testing.T) {
+	mc := NewMessageChannel(10)
+	defer mc.Close()
+
+	var calls []string
+	mc.OnDone(func(agentID string, doneCount int) {
+		calls = append(calls, agentID)
+		if doneCount == 2 {
+			mc.Close()
+		}
+	})
+
+	if n := mc.MarkDone("agent1"); n != 1 {
+		t.Errorf("expected 1 done agent, got %d", n)
+	}
+	// Marking the same agent twice does not double count
+	if n := mc.MarkDone("agent1"); n != 1 {
+		t.Errorf("expected 1 done agent, got %d", n)
+	}
+	if mc.IsClosed() {
+		t.Error("channel should still be open")
+	}
+
+	mc.MarkDone("agent2")
+	if !mc.IsClosed() {
+		t.Error("callback should have closed the channel")
+	}
+	if !mc.IsDone("agent2") || mc.IsDone("agent3") {
+		t.Error("IsDone reported the wrong agents")
+	}
+	if len(calls) != 3 {
+		t.Errorf("expected 3 callback calls, got %d", len(calls))
+	}
+}
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// Checkpoint captures enough of a workflow run to resume it after a
+// failure without re-running the steps that already finished.
+type Checkpoint struct {
+	CompletedSteps []string               `json:"completed_steps"`            // Keys of finished steps
+	CurrentStep    int                    `json:"current_step"`               // Engine progress counter
+	TotalSteps     int                    `json:"total_steps"`                // Engine step total
+	SessionHistory []types.ChatMessage    `json:"session_messages,omitempty"` // Prior-session context the run started with
+	History        []CheckpointOutput     `json:"history"`                    // Agent outputs in order
+	Shared         map[string]interface{} `json:"shared,omitempty"`           // Shared memory snapshot
+	Stats          *RunStats              `json:"stats,omitempty"`            // Usage so far, carried into the resumed run
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// CheckpointOutput is one agent output from the run's context history
+type CheckpointOutput struct {
+	AgentID   string    `json:"agent_id"`
+	Response  string    `json:"response"`
+	Iteration int       `json:"iteration,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -6,4 +6,7 @@ import "errors"
 var (
 	// ErrChannelClosed is returned when trying to send on a closed MessageChannel
 	ErrChannelClosed = errors.New("message channel is closed")
+
+	// ErrBudgetExceeded is returned when a run reaches a workflow budget limit
+	ErrBudgetExceeded = errors.New("budget exceeded")
 )
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"sort"
 	"time"
+
+	"Orkflow/pkg/types"
 )
 
 const (
@@ -17,10 +19,20 @@ const (
 	SessionsFolder = ".orka/sessions"
 )
 
+// Session statuses recorded when a run ends
+const (
+	StatusCompleted      = "completed"
+	StatusFailed         = "failed"
+	StatusCancelled      = "cancelled"
+	StatusBudgetExceeded = "budget_exceeded"
+)
+
 type Message struct {
 	AgentID   string    `json:"agent_id"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
+	Iteration int       `json:"iteration,omitempty"` // Loop iteration that produced the message (1-based)
+	Model     string    `json:"model,omitempty"`     // provider/model that produced the message
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -30,6 +42,11 @@ type Session struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Messages  []Message `json:"messages"`
+	Status    string    `json:"status,omitempty"` // How the last run ended
+	Reason    string    `json:"reason,omitempty"` // Why the last run stopped early
+	Stats     *RunStats `json:"stats,omitempty"`  // Timing and token usage of the last run
+
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"` // Progress of an unfinished run, for orka resume
 }
 
 // GetSessionsDir returns the path to sessions directory
@@ -67,18 +84,28 @@ func (s *Session) AddMessage(agentID, role, content string) {
 	s.UpdatedAt = time.Now()
 }
 
-// GetHistory returns formatted history for context
-func (s *Session) GetHistory() string {
-	if len(s.Messages) == 0 {
-		return ""
+// AppendMessage appends a fully populated message to the session
+func (s *Session) AppendMessage(msg Message) {
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
 	}
+	s.Messages = append(s.Messages, msg)
+	s.UpdatedAt = time.Now()
+}
 
-	var result string
-	result = "=== Previous Session Context ===\n\n"
-	for _, msg := range s.Messages {
-		result += fmt.Sprintf("[%s] %s:\n%s\n\n", msg.AgentID, msg.Role, msg.Content)
+// GetHistory returns the session's messages as conversation context. Each
+// agent output becomes a user message labelled with the agent and its role,
+// since the model being prompted did not write them.
+func (s *Session) GetHistory() []types.ChatMessage {
+	history := make([]types.ChatMessage, 0, len(s.Messages))
+	for i, msg := range s.Messages {
+		content := fmt.Sprintf("[%s] %s:\n%s", msg.AgentID, msg.Role, msg.Content)
+		if i == 0 {
+			content = "=== Previous Session Context ===\n\n" + content
+		}
+		history = append(history, types.ChatMessage{Role: types.RoleUser, Content: content})
 	}
-	return result
+	return history
 }
 
 // Save persists the session to disk
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// RunStats summarizes the timing and token usage of a workflow run
+type RunStats struct {
+	Duration      time.Duration `json:"duration"`
+	InputTokens   int           `json:"input_tokens"`
+	OutputTokens  int           `json:"output_tokens"`
+	EstimatedCost float64       `json:"estimated_cost,omitempty"` // USD, when the models are priced
+	Agents        []AgentStats  `json:"agents,omitempty"`
+}
+
+// AgentStats is the usage of one agent across all its runs
+type AgentStats struct {
+	AgentID           string        `json:"agent_id"`
+	Role              string        `json:"role,omitempty"`
+	Provider          string        `json:"provider,omitempty"`
+	Model             string        `json:"model,omitempty"`
+	Runs              int           `json:"runs"`
+	Duration          time.Duration `json:"duration"`
+	InputTokens       int           `json:"input_tokens"`
+	CachedInputTokens int           `json:"cached_input_tokens,omitempty"`
+	OutputTokens      int           `json:"output_tokens"`
+	Calls             []CallStats   `json:"calls,omitempty"` // Every model call, in order
+}
+
+// CallStats is the usage of a single model call
+type CallStats struct {
+	Provider          string        `json:"provider,omitempty"` // Model that served the call, which differs
+	Model             string        `json:"model,omitempty"`    // from the agent's after a fallback
+	InputTokens       int           `json:"input_tokens"`
+	CachedInputTokens int           `json:"cached_input_tokens,omitempty"`
+	OutputTokens      int           `json:"output_tokens"`
+	Duration          time.Duration `json:"duration"`
+}
+
+// Usage returns the token usage of the call
+func (c CallStats) Usage() types.Usage {
+	return types.Usage{
+		InputTokens:       c.InputTokens,
+		CachedInputTokens: c.CachedInputTokens,
+		OutputTokens:      c.OutputTokens,
+	}
+}
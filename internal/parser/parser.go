@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"Orkflow/pkg/types"
 
@@ -9,6 +12,27 @@ import (
 )
 
 func ParseYAML(path string) (*types.WorkflowConfig, error) {
+	return parseFile(path, nil)
+}
+
+// parseFile parses and validates a workflow file, then resolves its
+// sub-workflow steps. stack holds the absolute paths of the files currently
+// being parsed and is used to detect reference cycles.
+func parseFile(path string, stack []string) (*types.WorkflowConfig, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stack {
+		if p == absPath {
+			cycle := append(append([]string{}, stack[i:]...), absPath)
+			for j := range cycle {
+				cycle[j] = filepath.Base(cycle[j])
+			}
+			return nil, fmt.Errorf("workflow reference cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -20,11 +44,95 @@ func ParseYAML(path string) (*types.WorkflowConfig, error) {
 		return nil, err
 	}
 
+	if err := loadOutputSchemas(&config, filepath.Dir(absPath)); err != nil {
+		return nil, err
+	}
+
 	err = validate(&config)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.Workflow != nil {
+		stack = append(stack, absPath)
+		if err := resolveWorkflow(config.Workflow, filepath.Dir(absPath), stack); err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
 
+// resolveWorkflow parses every sub-workflow referenced by the spec's steps
+func resolveWorkflow(wf *types.WorkflowSpec, dir string, stack []string) error {
+	if err := resolveSteps(wf.Steps, dir, stack); err != nil {
+		return err
+	}
+	if wf.Then != nil {
+		return resolveStep(wf.Then, dir, stack)
+	}
+	return nil
+}
+
+func resolveSteps(steps []types.Step, dir string, stack []string) error {
+	for i := range steps {
+		if err := resolveStep(&steps[i], dir, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveStep(step *types.Step, dir string, stack []string) error {
+	switch {
+	case step.Workflow != "":
+		path := step.Workflow
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		child, err := parseFile(path, stack)
+		if err != nil {
+			return fmt.Errorf("sub-workflow %s: %w", step.Workflow, err)
+		}
+		if child.Workflow == nil {
+			return fmt.Errorf("sub-workflow %s has no workflow section", step.Workflow)
+		}
+		step.SubWorkflow = child
+	case step.Switch != nil:
+		for _, steps := range step.Switch.Cases {
+			if err := resolveSteps(steps, dir, stack); err != nil {
+				return err
+			}
+		}
+		return resolveSteps(step.Switch.Default, dir, stack)
+	case step.Loop != nil:
+		return resolveSteps(step.Loop.Steps, dir, stack)
+	case step.ForEach != nil && step.ForEach.Then != nil:
+		return resolveStep(step.ForEach.Then, dir, stack)
+	}
+	return nil
+}
+
+// loadOutputSchemas reads the agents' output schemas given as file paths,
+// relative to dir. Files may be JSON or YAML.
+func loadOutputSchemas(config *types.WorkflowConfig, dir string) error {
+	for i := range config.Agents {
+		agent := &config.Agents[i]
+		if agent.OutputSchema == nil || agent.OutputSchema.Path == "" {
+			continue
+		}
+		path := agent.OutputSchema.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("agent %s: output_schema: %w", agent.ID, err)
+		}
+		// YAML is a superset of JSON, so one decoder reads both
+		if err := yaml.Unmarshal(data, &agent.OutputSchema.Schema); err != nil {
+			return fmt.Errorf("agent %s: output_schema %s: %w", agent.ID, agent.OutputSchema.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeWorkflow(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseYAML_SubWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "lib/research.yaml", `
+agents:
+  - id: researcher
+  - id: writer
+workflow:
+  type: sequential
+  steps:
+    - agent: researcher
+    - agent: writer
+`)
+	path := writeWorkflow(t, dir, "main.yaml", `
+agents:
+  - id: planner
+workflow:
+  type: sequential
+  steps:
+    - agent: planner
+    - workflow: lib/research.yaml
+      inputs:
+        topic: plan
+      outputs:
+        article: draft
+`)
+
+	config, err := ParseYAML(path)
+	if err != nil {
+		t.Fatalf("ParseYAML() error: %v", err)
+	}
+
+	step := config.Workflow.Steps[1]
+	if step.SubWorkflow == nil {
+		t.Fatal("expected sub-workflow to be resolved")
+	}
+	if len(step.SubWorkflow.Agents) != 2 {
+		t.Errorf("expected 2 child agents, got %d", len(step.SubWorkflow.Agents))
+	}
+	if step.NodeID() != "research" {
+		t.Errorf("expected node id from file name, got %q", step.NodeID())
+	}
+	if step.Inputs["topic"] != "plan" || step.Outputs["article"] != "draft" {
+		t.Errorf("unexpected key mappings: %v %v", step.Inputs, step.Outputs)
+	}
+}
+
+func TestParseYAML_SubWorkflowCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "a.yaml", `
+agents:
+  - id: x
+workflow:
+  type: sequential
+  steps:
+    - workflow: b.yaml
+`)
+	writeWorkflow(t, dir, "b.yaml", `
+agents:
+  - id: y
+workflow:
+  type: sequential
+  steps:
+    - agent: y
+    - workflow: a.yaml
+`)
+
+	_, err := ParseYAML(filepath.Join(dir, "a.yaml"))
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "a.yaml -> b.yaml -> a.yaml") {
+		t.Errorf("expected cycle path in error, got %v", err)
+	}
+}
+
+func TestParseYAML_SubWorkflowMissing(t *testing.T) {
+	dir := t.TempDir()
+	path := writeWorkflow(t, dir, "main.yaml", `
+agents:
+  - id: x
+workflow:
+  type: sequential
+  steps:
+    - workflow: missing.yaml
+`)
+
+	if _, err := ParseYAML(path); err == nil {
+		t.Fatal("expected error for missing sub-workflow")
+	}
+}
+
+func TestParseYAML_Timeouts(t *testing.T) {
+	path := writeWorkflow(t, t.TempDir(), "main.yaml", `
+agents:
+  - id: a
+    timeout: 90s
+workflow:
+  type: sequential
+  timeout: 10m
+  steps:
+    - agent: a
+`)
+
+	config, err := ParseYAML(path)
+	if err != nil {
+		t.Fatalf("ParseYAML() error: %v", err)
+	}
+	if config.Agents[0].Timeout != 90*time.Second {
+		t.Errorf("agent timeout = %v, want 90s", config.Agents[0].Timeout)
+	}
+	if config.Workflow.Timeout != 10*time.Minute {
+		t.Errorf("workflow timeout = %v, want 10m", config.Workflow.Timeout)
+	}
+}
+
+func TestParseYAML_GenerationParams(t *testing.T) {
+	path := writeWorkflow(t, t.TempDir(), "params.yaml", `
+models:
+  gpt:
+    provider: openai
+    model: gpt-4o-mini
+    temperature: 0
+    max_tokens: 512
+    seed: 42
+agents:
+  - id: writer
+    model: gpt
+    temperature: 0.7
+    stop: ["END"]
+`)
+
+	config, err := ParseYAML(path)
+	if err != nil {
+		t.Fatalf("ParseYAML() error: %v", err)
+	}
+
+	model := config.Models["gpt"]
+	if model.Temperature == nil || *model.Temperature != 0 || model.MaxTokens != 512 || model.Seed == nil || *model.Seed != 42 {
+		t.Errorf("unexpected model params: %+v", model.GenerationParams)
+	}
+	params := model.GenerationParams.Merge(config.Agents[0].GenerationParams)
+	if *params.Temperature != 0.7 || params.MaxTokens != 512 || len(params.Stop) != 1 {
+		t.Errorf("expected the agent to override the temperature only, got %+v", params)
+	}
+}
+
+func TestParseYAML_OutputSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkflow(t, dir, "schemas/review.json", `{"type": "object", "required": ["score"], "properties": {"score": {"type": "integer"}}}`)
+	path := writeWorkflow(t, dir, "main.yaml", `
+agents:
+  - id: reviewer
+    model: m
+    output_schema: schemas/review.json
+  - id: tagger
+    model: m
+    output_schema:
+      type: array
+      items: {type: string}
+`)
+
+	config, err := ParseYAML(path)
+	if err != nil {
+		t.Fatalf("ParseYAML() error: %v", err)
+	}
+	if schema := config.Agents[0].OutputSchema; schema == nil || schema.Schema["type"] != "object" {
+		t.Errorf("expected the schema file to be loaded, got %+v", schema)
+	}
+	if schema := config.Agents[1].OutputSchema; schema == nil || schema.Schema["type"] != "array" {
+		t.Errorf("expected the inline schema, got %+v", schema)
+	}
+
+	invalid := writeWorkflow(t, dir, "invalid.yaml", `
+agents:
+  - id: reviewer
+    model: m
+    output_schema:
+      type: 5
+`)
+	if _, err := ParseYAML(invalid); err == nil || !strings.Contains(err.Error(), "output_schema") {
+		t.Errorf("expected an output_schema error, got %v", err)
+	}
+}
@@ -2,8 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	"Orkflow/internal/schema"
 	"Orkflow/pkg/types"
+
+	"github.com/expr-lang/expr"
 )
 
 func validate(config *types.WorkflowConfig) error {
@@ -20,6 +25,42 @@ func validate(config *types.WorkflowConfig) error {
 			return fmt.Errorf("duplicate agent id: %s", agent.ID)
 		}
 		agentIDs[agent.ID] = true
+		if err := validateRetry(agent.Retry); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.ID, err)
+		}
+		if err := validateParams(agent.GenerationParams); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.ID, err)
+		}
+		if err := validateOutputSchema(&agent); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.ID, err)
+		}
+	}
+	for name, model := range config.Models {
+		if err := validateRetry(model.Retry); err != nil {
+			return fmt.Errorf("model %s: %w", name, err)
+		}
+		if err := validateParams(model.GenerationParams); err != nil {
+			return fmt.Errorf("model %s: %w", name, err)
+		}
+		for _, fallback := range model.Fallbacks {
+			if _, ok := config.Models[fallback]; !ok {
+				return fmt.Errorf("model %s: unknown fallback model: %s", name, fallback)
+			}
+			if fallback == name {
+				return fmt.Errorf("model %s lists itself in fallbacks", name)
+			}
+		}
+	}
+
+	for _, agent := range config.Agents {
+		for _, sub := range agent.SubAgents {
+			if !agentIDs[sub] {
+				return fmt.Errorf("unknown agent in sub_agents of %s: %s", agent.ID, sub)
+			}
+			if sub == agent.ID {
+				return fmt.Errorf("agent %s lists itself in sub_agents", agent.ID)
+			}
+		}
 	}
 
 	if config.Workflow != nil {
@@ -27,25 +68,428 @@ func validate(config *types.WorkflowConfig) error {
 			return err
 		}
 	}
+	if config.Budget != nil {
+		if err := validateBudget(config.Budget); err != nil {
+			return err
+		}
+	}
+	if config.FileAccess != nil {
+		if err := validateFileAccess(config.FileAccess); err != nil {
+			return err
+		}
+	}
+	if config.Shell != nil {
+		if err := validateShell(config.Shell); err != nil {
+			return err
+		}
+	}
+	if config.HTTP != nil {
+		if err := validateHTTP(config.HTTP); err != nil {
+			return err
+		}
+	}
+	if config.Script != nil && (config.Script.MaxAllocs < 0 || config.Script.MaxSteps < 0) {
+		return fmt.Errorf("script max_allocs and max_steps must not be negative")
+	}
+	for _, agent := range config.Agents {
+		for _, tool := range agent.Tools {
+			if tool == "shell" && config.Shell == nil {
+				return fmt.Errorf("agent %s lists the shell tool, which needs a shell section", agent.ID)
+			}
+			if tool == "http" && config.HTTP == nil {
+				return fmt.Errorf("agent %s lists the http tool, which needs an http section", agent.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func validateRetry(retry *types.RetryConfig) error {
+	if retry == nil {
+		return nil
+	}
+	if retry.MaxAttempts < 0 || retry.InitialDelay < 0 || retry.MaxDelay < 0 || retry.Multiplier < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	for _, kind := range retry.RetryOn {
+		if !kind.Valid() {
+			return fmt.Errorf("unknown error kind in retry_on: %s", kind)
+		}
+	}
+	return nil
+}
+
+func validateParams(params types.GenerationParams) error {
+	if params.Temperature != nil && (*params.Temperature < 0 || *params.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if params.TopP != nil && (*params.TopP < 0 || *params.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if params.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative")
+	}
+	switch params.ResponseFormat {
+	case "", types.ResponseFormatText, types.ResponseFormatJSON:
+	default:
+		return fmt.Errorf("unknown response_format: %s (use text or json)", params.ResponseFormat)
+	}
+	return nil
+}
+
+func validateOutputSchema(agent *types.Agent) error {
+	if agent.OutputSchema == nil {
+		return nil
+	}
+	if len(agent.OutputSchema.Schema) == 0 {
+		return fmt.Errorf("output_schema is empty")
+	}
+	if agent.IsSupervisor() {
+		return fmt.Errorf("output_schema is not supported on supervisors")
+	}
+	if agent.ResponseFormat == types.ResponseFormatText {
+		return fmt.Errorf("response_format text conflicts with output_schema")
+	}
+	if _, err := schema.Compile(agent.OutputSchema.Schema); err != nil {
+		return fmt.Errorf("output_schema: %w", err)
+	}
+	return nil
+}
+
+func validateBudget(budget *types.BudgetConfig) error {
+	if budget.MaxTokens < 0 {
+		return fmt.Errorf("budget max_tokens must not be negative")
+	}
+	if budget.MaxCostUSD < 0 {
+		return fmt.Errorf("budget max_cost_usd must not be negative")
+	}
+	if budget.MaxCalls < 0 {
+		return fmt.Errorf("budget max_calls must not be negative")
+	}
+	return nil
+}
+
+func validateFileAccess(access *types.FileAccessConfig) error {
+	if len(access.Roots) == 0 {
+		return fmt.Errorf("file_access needs at least one root")
+	}
+	switch access.Mode {
+	case "", types.FileAccessReadOnly, types.FileAccessReadWrite:
+	default:
+		return fmt.Errorf("unknown file_access mode: %s (use read-only or read-write)", access.Mode)
+	}
+	if access.MaxFileSize < 0 {
+		return fmt.Errorf("file_access max_file_size must not be negative")
+	}
+	for _, pattern := range access.Deny {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file_access deny pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func validateShell(shell *types.ShellConfig) error {
+	if len(shell.Allow) == 0 {
+		return fmt.Errorf("shell needs at least one allowed command")
+	}
+	for _, entry := range shell.Allow {
+		if strings.TrimSpace(entry) == "" {
+			return fmt.Errorf("shell allow entries must not be empty")
+		}
+	}
+	if shell.Timeout < 0 || shell.MaxOutput < 0 {
+		return fmt.Errorf("shell timeout and max_output must not be negative")
+	}
+	return nil
+}
+
+func validateHTTP(http *types.HTTPConfig) error {
+	if len(http.AllowHosts) == 0 {
+		return fmt.Errorf("http needs at least one allowed host")
+	}
+	for _, host := range http.AllowHosts {
+		if strings.TrimSpace(host) == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("http allow_hosts entry %q must be a host, not a URL", host)
+		}
+	}
+	for _, header := range http.Headers {
+		if header.Name == "" || header.Env == "" {
+			return fmt.Errorf("http headers need a name and an env variable")
+		}
+	}
+	if http.Timeout < 0 || http.MaxResponse < 0 {
+		return fmt.Errorf("http timeout and max_response must not be negative")
+	}
 	return nil
 }
 
 func validateWorkflow(wf *types.WorkflowSpec, agentIDs map[string]bool) error {
-	if wf.Type != "sequential" && wf.Type != "parallel" {
+	switch wf.Type {
+	case "sequential", "parallel":
+	case "collaborative":
+		if err := validateCollaborative(wf, agentIDs); err != nil {
+			return err
+		}
+	case "dag":
+		if err := validateDAG(wf.Steps); err != nil {
+			return err
+		}
+	default:
 		return fmt.Errorf("invalid workflow type: %s", wf.Type)
 	}
-	for _, step := range wf.Steps {
-		if !agentIDs[step.Agent] {
-			return fmt.Errorf("unknown agent in steps: %s", step.Agent)
-		}
+	if err := validateSteps(wf.Steps, agentIDs, wf.Type == "dag"); err != nil {
+		return err
 	}
 	for _, branch := range wf.Branches {
 		if !agentIDs[branch] {
 			return fmt.Errorf("unknown agent in branches: %s", branch)
 		}
 	}
-	if wf.Then != nil && !agentIDs[wf.Then.Agent] {
-		return fmt.Errorf("unknown agent in then: %s", wf.Then.Agent)
+	if wf.Then != nil {
+		if !agentIDs[wf.Then.Agent] {
+			return fmt.Errorf("unknown agent in then: %s", wf.Then.Agent)
+		}
+		if err := validateStep(wf.Then, agentIDs, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSteps checks a list of steps, recursing into switch cases.
+// allowDeps is true only for the top-level steps of a dag workflow.
+func validateSteps(steps []types.Step, agentIDs map[string]bool, allowDeps bool) error {
+	for i := range steps {
+		if err := validateStep(&steps[i], agentIDs, allowDeps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateStep(step *types.Step, agentIDs map[string]bool, allowDeps bool) error {
+	if len(step.DependsOn) > 0 && !allowDeps {
+		return fmt.Errorf("depends_on is only supported in dag workflows (step %s)", step.NodeID())
+	}
+	if step.When != "" {
+		if _, err := expr.Compile(step.When); err != nil {
+			return fmt.Errorf("invalid when expression in step %s: %w", step.NodeID(), err)
+		}
+	}
+
+	if step.Loop != nil {
+		return validateLoop(step, agentIDs)
+	}
+	if step.ForEach != nil {
+		return validateForEach(step, agentIDs)
+	}
+	if step.Workflow != "" {
+		return validateSubWorkflow(step)
+	}
+
+	if step.Switch == nil {
+		if !agentIDs[step.Agent] {
+			return fmt.Errorf("unknown agent in steps: %s", step.Agent)
+		}
+		return nil
+	}
+
+	sw := step.Switch
+	if step.Agent != "" {
+		return fmt.Errorf("step %s cannot set both agent and switch", step.NodeID())
+	}
+	if !agentIDs[sw.Agent] {
+		return fmt.Errorf("unknown agent in switch: %s", sw.Agent)
+	}
+	if len(sw.Cases) == 0 {
+		return fmt.Errorf("switch %s has no cases", sw.Agent)
+	}
+	for route, steps := range sw.Cases {
+		if err := validateSteps(steps, agentIDs, false); err != nil {
+			return fmt.Errorf("switch %s case %s: %w", sw.Agent, route, err)
+		}
+	}
+	if err := validateSteps(sw.Default, agentIDs, false); err != nil {
+		return fmt.Errorf("switch %s default: %w", sw.Agent, err)
+	}
+	return nil
+}
+
+func validateLoop(step *types.Step, agentIDs map[string]bool) error {
+	loop := step.Loop
+	if step.Agent != "" || step.Switch != nil {
+		return fmt.Errorf("loop step %s cannot also set agent or switch", step.NodeID())
+	}
+	if len(loop.Steps) == 0 {
+		return fmt.Errorf("loop %s has no steps", step.NodeID())
+	}
+	if loop.MaxIterations < 0 {
+		return fmt.Errorf("loop %s: max_iterations must be positive", step.NodeID())
+	}
+	if loop.Until != "" {
+		if _, err := expr.Compile(loop.Until); err != nil {
+			return fmt.Errorf("invalid until expression in loop %s: %w", step.NodeID(), err)
+		}
+	}
+	if err := validateSteps(loop.Steps, agentIDs, false); err != nil {
+		return fmt.Errorf("loop %s: %w", step.NodeID(), err)
+	}
+	return nil
+}
+
+func validateForEach(step *types.Step, agentIDs map[string]bool) error {
+	fe := step.ForEach
+	if step.Agent != "" || step.Switch != nil {
+		return fmt.Errorf("foreach step %s cannot also set agent or switch", step.NodeID())
+	}
+	if fe.Items == "" {
+		return fmt.Errorf("foreach %s missing items key", step.NodeID())
+	}
+	if !agentIDs[fe.Agent] {
+		return fmt.Errorf("unknown agent in foreach: %s", fe.Agent)
+	}
+	if fe.MaxParallel < 0 {
+		return fmt.Errorf("foreach %s: max_parallel must be positive", step.NodeID())
+	}
+	if fe.Then != nil {
+		if err := validateStep(fe.Then, agentIDs, false); err != nil {
+			return fmt.Errorf("foreach %s then: %w", step.NodeID(), err)
+		}
+	}
+	return nil
+}
+
+// validateSubWorkflow checks a workflow reference step. The referenced file
+// itself is parsed and validated when the parser resolves it.
+func validateSubWorkflow(step *types.Step) error {
+	if step.Agent != "" || step.Switch != nil {
+		return fmt.Errorf("workflow step %s cannot also set agent or switch", step.NodeID())
+	}
+	for child, parent := range step.Inputs {
+		if child == "" || parent == "" {
+			return fmt.Errorf("workflow step %s: empty key in inputs", step.NodeID())
+		}
+	}
+	for parent, child := range step.Outputs {
+		if child == "" || parent == "" {
+			return fmt.Errorf("workflow step %s: empty key in outputs", step.NodeID())
+		}
+	}
+	return nil
+}
+
+// validateCollaborative checks the participant set and termination policy
+func validateCollaborative(wf *types.WorkflowSpec, agentIDs map[string]bool) error {
+	if len(wf.Collaborators) < 2 {
+		return fmt.Errorf("collaborative workflow needs at least 2 collaborators")
+	}
+
+	members := make(map[string]bool, len(wf.Collaborators))
+	for _, id := range wf.Collaborators {
+		if !agentIDs[id] {
+			return fmt.Errorf("unknown agent in collaborators: %s", id)
+		}
+		if members[id] {
+			return fmt.Errorf("duplicate collaborator: %s", id)
+		}
+		members[id] = true
+	}
+
+	switch wf.Termination {
+	case "", "all":
+	case "quorum":
+		if wf.Quorum < 0 || wf.Quorum > len(wf.Collaborators) {
+			return fmt.Errorf("quorum must be between 1 and %d, or 0 for a majority (the default)", len(wf.Collaborators))
+		}
+	case "moderator":
+		if wf.Moderator == "" {
+			return fmt.Errorf("termination: moderator requires a moderator agent")
+		}
+		if !members[wf.Moderator] {
+			return fmt.Errorf("moderator must be one of the collaborators: %s", wf.Moderator)
+		}
+	default:
+		return fmt.Errorf("invalid termination policy: %s", wf.Termination)
+	}
+	return nil
+}
+
+// validateDAG checks that every depends_on edge points at a known node and
+// that the graph has no cycles.
+func validateDAG(steps []types.Step) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("dag workflow has no steps")
+	}
+
+	nodes := make(map[string]*types.Step, len(steps))
+	for i := range steps {
+		id := steps[i].NodeID()
+		if id == "" {
+			return fmt.Errorf("dag step %d missing id or agent", i)
+		}
+		if nodes[id] != nil {
+			return fmt.Errorf("duplicate dag node: %s", id)
+		}
+		nodes[id] = &steps[i]
+	}
+
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if dep == step.NodeID() {
+				return fmt.Errorf("dag node %s depends on itself", dep)
+			}
+			if nodes[dep] == nil {
+				return fmt.Errorf("dag node %s depends on unknown node: %s", step.NodeID(), dep)
+			}
+		}
+	}
+
+	// Depth-first search, tracking the current path to report the cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(steps))
+	var path []string
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch marks[id] {
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == id {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), id)
+			return fmt.Errorf("dag has a cycle: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+
+		marks[id] = visiting
+		path = append(path, id)
+		for _, dep := range nodes[id].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[id] = visited
+		return nil
+	}
+
+	for _, step := range steps {
+		if err := visit(step.NodeID()); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func dagConfig(steps ...types.Step) *types.WorkflowConfig {
+	return &types.WorkflowConfig{
+		Agents: []types.Agent{
+			{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"},
+		},
+		Workflow: &types.WorkflowSpec{
+			Type:  "dag",
+			Steps: steps,
+		},
+	}
+}
+
+func TestValidateDAG_Valid(t *testing.T) {
+	config := dagConfig(
+		types.Step{Agent: "a"},
+		types.Step{Agent: "b"},
+		types.Step{Agent: "c", DependsOn: []string{"a"}},
+		types.Step{Agent: "d", DependsOn: []string{"b", "c"}},
+	)
+
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid dag, got %v", err)
+	}
+}
+
+func TestValidateDAG_Cycle(t *testing.T) {
+	config := dagConfig(
+		types.Step{Agent: "a", DependsOn: []string{"c"}},
+		types.Step{Agent: "b", DependsOn: []string{"a"}},
+		types.Step{Agent: "c", DependsOn: []string{"b"}},
+	)
+
+	err := validate(config)
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestValidateDAG_SelfDependency(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a", DependsOn: []string{"a"}})
+
+	if err := validate(config); err == nil {
+		t.Fatal("expected self-dependency error")
+	}
+}
+
+func TestValidateDAG_UnknownNode(t *testing.T) {
+	config := dagConfig(
+		types.Step{Agent: "a"},
+		types.Step{Agent: "b", DependsOn: []string{"missing"}},
+	)
+
+	err := validate(config)
+	if err == nil || !strings.Contains(err.Error(), "unknown node") {
+		t.Errorf("expected unknown node error, got %v", err)
+	}
+}
+
+func TestValidateDAG_NodeIDs(t *testing.T) {
+	// The same agent can appear twice when the nodes have distinct ids
+	config := dagConfig(
+		types.Step{ID: "draft", Agent: "a"},
+		types.Step{ID: "revise", Agent: "a", DependsOn: []string{"draft"}},
+	)
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid dag, got %v", err)
+	}
+
+	config = dagConfig(
+		types.Step{Agent: "a"},
+		types.Step{Agent: "a"},
+	)
+	if err := validate(config); err == nil {
+		t.Error("expected duplicate node error")
+	}
+}
+
+func TestValidate_DependsOnOutsideDAG(t *testing.T) {
+	config := dagConfig(
+		types.Step{Agent: "a"},
+		types.Step{Agent: "b", DependsOn: []string{"a"}},
+	)
+	config.Workflow.Type = "sequential"
+
+	if err := validate(config); err == nil {
+		t.Error("expected depends_on to be rejected in sequential workflow")
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.Budget = &types.BudgetConfig{MaxTokens: 1000, MaxCostUSD: 0.5}
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid budget, got %v", err)
+	}
+
+	config.Budget.MaxCalls = -1
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "max_calls") {
+		t.Errorf("expected max_calls error, got %v", err)
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.Agents[0].Retry = &types.RetryConfig{RetryOn: []types.ErrorKind{types.ErrorRateLimited, "timeout"}}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected unknown error kind error, got %v", err)
+	}
+
+	config.Agents[0].Retry = &types.RetryConfig{Jitter: 1.5}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "jitter") {
+		t.Errorf("expected jitter error, got %v", err)
+	}
+}
+
+func TestValidateFallbacks(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.Models = map[string]types.Model{
+		"gemini": {Provider: "google", Model: "gemini-2.0-flash", Fallbacks: []string{"gpt"}},
+		"gpt":    {Provider: "openai", Model: "gpt-4o-mini"},
+	}
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid fallbacks, got %v", err)
+	}
+
+	config.Models["gpt"] = types.Model{Provider: "openai", Model: "gpt-4o-mini", Fallbacks: []string{"claude"}}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "claude") {
+		t.Errorf("expected unknown fallback error, got %v", err)
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	temperature := 3.0
+	config.Agents[0].Temperature = &temperature
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "temperature") {
+		t.Errorf("expected temperature error, got %v", err)
+	}
+
+	config.Agents[0].Temperature = nil
+	config.Agents[0].ResponseFormat = "xml"
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "response_format") {
+		t.Errorf("expected response_format error, got %v", err)
+	}
+}
+
+func TestValidateFileAccess(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.FileAccess = &types.FileAccessConfig{Roots: []string{"./workspace"}, Mode: types.FileAccessReadWrite, Deny: []string{".env", "*.pem"}}
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid file_access, got %v", err)
+	}
+
+	config.FileAccess.Mode = "write-only"
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "mode") {
+		t.Errorf("expected mode error, got %v", err)
+	}
+
+	config.FileAccess = &types.FileAccessConfig{Roots: []string{"."}, Deny: []string{"[.env"}}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "deny pattern") {
+		t.Errorf("expected deny pattern error, got %v", err)
+	}
+}
+
+func TestValidateShell(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.Agents[0].Tools = []string{"shell"}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "shell section") {
+		t.Errorf("expected a missing shell section error, got %v", err)
+	}
+
+	config.Shell = &types.ShellConfig{Allow: []string{"go test", "git diff"}}
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid shell config, got %v", err)
+	}
+
+	config.Shell.Allow = nil
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "allowed command") {
+		t.Errorf("expected an empty allow-list error, got %v", err)
+	}
+}
+
+func TestValidateHTTP(t *testing.T) {
+	config := dagConfig(types.Step{Agent: "a"})
+	config.Agents[0].Tools = []string{"http"}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "http section") {
+		t.Errorf("expected a missing http section error, got %v", err)
+	}
+
+	config.HTTP = &types.HTTPConfig{
+		AllowHosts: []string{"api.github.com", "*.example.com"},
+		Headers:    []types.HTTPHeaderConfig{{Name: "Authorization", Env: "GITHUB_TOKEN", Prefix: "Bearer "}},
+	}
+	if err := validate(config); err != nil {
+		t.Fatalf("expected valid http config, got %v", err)
+	}
+
+	config.HTTP.AllowHosts = []string{"https://api.github.com/"}
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "must be a host") {
+		t.Errorf("expected a URL in allow_hosts to be rejected, got %v", err)
+	}
+
+	config.HTTP.AllowHosts = []string{"api.github.com"}
+	config.HTTP.Headers[0].Env = ""
+	if err := validate(config); err == nil || !strings.Contains(err.Error(), "env variable") {
+		t.Errorf("expected a header without env to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package pricing
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"Orkflow/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the pricing file format this build understands
+const Version = 1
+
+// OverrideFolder holds user pricing overrides, relative to the home directory
+const OverrideFolder = ".orka"
+
+//go:embed pricing.yaml
+var defaultPricing []byte
+
+// Rate is the price of a model in USD per 1M tokens
+type Rate struct {
+	Input       float64 `yaml:"input"`
+	Output      float64 `yaml:"output"`
+	CachedInput float64 `yaml:"cached_input,omitempty"` // Defaults to Input when zero
+}
+
+// Cost prices the usage of one or more calls. Cached input tokens are part
+// of InputTokens and charged at the cached rate when there is one.
+func (r Rate) Cost(usage types.Usage) float64 {
+	cachedRate := r.CachedInput
+	if cachedRate == 0 {
+		cachedRate = r.Input
+	}
+	uncached := usage.InputTokens - usage.CachedInputTokens
+	return (float64(uncached)*r.Input +
+		float64(usage.CachedInputTokens)*cachedRate +
+		float64(usage.OutputTokens)*r.Output) / 1000000
+}
+
+// Table maps providers to the rates of their models
+type Table struct {
+	Version   int                        `yaml:"version"`
+	Providers map[string]map[string]Rate `yaml:"providers"`
+}
+
+// providerAliases maps provider names accepted in workflows to the name
+// used in the pricing table
+var providerAliases = map[string]string{
+	"google": "gemini",
+	"claude": "anthropic",
+}
+
+// Default returns the pricing table shipped with orka
+func Default() *Table {
+	table, err := Parse(defaultPricing)
+	if err != nil {
+		panic(fmt.Sprintf("pricing: invalid built-in table: %v", err))
+	}
+	return table
+}
+
+// Parse reads a pricing table in YAML or JSON
+func Parse(data []byte) (*Table, error) {
+	var table Table
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	if table.Version == 0 {
+		return nil, fmt.Errorf("missing version")
+	}
+	if table.Version > Version {
+		return nil, fmt.Errorf("version %d is newer than supported version %d", table.Version, Version)
+	}
+	if table.Providers == nil {
+		table.Providers = make(map[string]map[string]Rate)
+	}
+	return &table, nil
+}
+
+// OverridePaths returns the user pricing files, in order of preference
+func OverridePaths() []string {
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, OverrideFolder)
+	return []string{filepath.Join(dir, "pricing.yaml"), filepath.Join(dir, "pricing.json")}
+}
+
+// Load returns the built-in table merged with the first user override file
+// that exists. The built-in table is returned alongside any error.
+func Load() (*Table, error) {
+	table := Default()
+	for _, path := range OverridePaths() {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return table, err
+		}
+		override, err := Parse(data)
+		if err != nil {
+			return table, fmt.Errorf("pricing file %s: %w", path, err)
+		}
+		table.Merge(override)
+		break
+	}
+	return table, nil
+}
+
+// Merge copies the rates of other into the table, replacing existing ones
+func (t *Table) Merge(other *Table) {
+	for provider, models := range other.Providers {
+		provider = normalizeProvider(provider)
+		if t.Providers[provider] == nil {
+			t.Providers[provider] = make(map[string]Rate)
+		}
+		for model, rate := range models {
+			t.Providers[provider][model] = rate
+		}
+	}
+}
+
+// Lookup finds the rate of a model. The provider's models are tried first,
+// by exact name then by longest prefix, then its "*" rate. Providers that
+// are not in the table, such as gateways like openrouter, fall back to the
+// model name alone with any "vendor/" prefix removed.
+func (t *Table) Lookup(provider, model string) (Rate, bool) {
+	provider = normalizeProvider(provider)
+	if models, ok := t.Providers[provider]; ok {
+		if rate, ok := matchModel(models, model); ok {
+			return rate, true
+		}
+		if rate, ok := models["*"]; ok {
+			return rate, true
+		}
+	}
+
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for name, models := range t.Providers {
+		if name == provider {
+			continue
+		}
+		if rate, ok := matchModel(models, model); ok {
+			return rate, true
+		}
+	}
+	return Rate{}, false
+}
+
+// Cost prices usage of a model, reporting false when the model is unpriced
+func (t *Table) Cost(provider, model string, usage types.Usage) (float64, bool) {
+	rate, ok := t.Lookup(provider, model)
+	if !ok {
+		return 0, false
+	}
+	return rate.Cost(usage), true
+}
+
+// matchModel finds a model by exact name or the longest name that the model
+// extends with a snapshot suffix, as in "gpt-4o-2024-08-06". Siblings such
+// as "gpt-4o-mini" don't match "gpt-4o", since they are priced differently.
+func matchModel(models map[string]Rate, model string) (Rate, bool) {
+	if rate, ok := models[model]; ok {
+		return rate, true
+	}
+	best := ""
+	for name := range models {
+		suffix, ok := strings.CutPrefix(model, name+"-")
+		if name != "*" && ok && isSnapshot(suffix) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Rate{}, false
+	}
+	return models[best], true
+}
+
+// isSnapshot reports whether a model name suffix names a release of the
+// same model: a date or version number, "latest", or a preview or
+// experimental build
+func isSnapshot(suffix string) bool {
+	if suffix == "" {
+		return false
+	}
+	if suffix[0] >= '0' && suffix[0] <= '9' {
+		return true
+	}
+	return suffix == "latest" || strings.HasPrefix(suffix, "preview") || strings.HasPrefix(suffix, "exp")
+}
+
+func normalizeProvider(provider string) string {
+	provider = strings.ToLower(provider)
+	if alias, ok := providerAliases[provider]; ok {
+		return alias
+	}
+	return provider
+}
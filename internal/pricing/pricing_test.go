@@ -0,0 +1,96 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestLookup(t *testing.T) {
+	table := Default()
+
+	tests := []struct {
+		provider, model string
+		want            float64 // Input rate
+		found           bool
+	}{
+		{"openai", "gpt-4o", 2.50, true},
+		{"openai", "gpt-4o-mini-2024-07-18", 0.15, true},     // Longest prefix wins
+		{"anthropic", "claude-3-5-sonnet-20241022", 3, true}, // Dated snapshot
+		{"google", "gemini-1.5-pro", 1.25, true},             // Provider alias
+		{"ollama", "llama3", 0, true},                        // Wildcard
+		{"openrouter", "openai/gpt-4o", 2.50, true},          // Gateway falls back to the model
+		{"google", "gemini-2.5-flash-lite", 0.10, true},      // Not priced as gemini-2.5-flash
+		{"google", "gemini-2.5-flash-preview-05-20", 0.30, true},
+		{"openai", "gpt-4.1-mini-2025-04-14", 0.40, true},
+		{"openai", "gpt-4o-audio", 0, false}, // Siblings don't inherit the parent's rate
+		{"openai", "unknown-model", 0, false},
+	}
+	for _, tt := range tests {
+		rate, ok := table.Lookup(tt.provider, tt.model)
+		if ok != tt.found || rate.Input != tt.want {
+			t.Errorf("Lookup(%s, %s) = %v, %v; want input %v, %v", tt.provider, tt.model, rate, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestMatchModelSiblings(t *testing.T) {
+	models := map[string]Rate{"model": {Input: 1}, "model-pro": {Input: 5}}
+	tests := []struct {
+		model string
+		want  float64
+		found bool
+	}{
+		{"model-20250101", 1, true},
+		{"model-latest", 1, true},
+		{"model-pro-preview-06", 5, true},
+		{"model-mini", 0, false},
+		{"model-lite", 0, false},
+		{"model-pro-lite", 0, false},
+	}
+	for _, tt := range tests {
+		rate, ok := matchModel(models, tt.model)
+		if ok != tt.found || rate.Input != tt.want {
+			t.Errorf("matchModel(%s) = %v, %v; want input %v, %v", tt.model, rate, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestRateCostCachedTokens(t *testing.T) {
+	rate := Rate{Input: 2, Output: 10, CachedInput: 1}
+	cost := rate.Cost(types.Usage{InputTokens: 1000000, CachedInputTokens: 500000, OutputTokens: 100000})
+	if math.Abs(cost-2.5) > 1e-9 {
+		t.Errorf("expected $2.50, got $%v", cost)
+	}
+
+	// Without a cached rate, cached tokens cost the same as input
+	rate.CachedInput = 0
+	cost = rate.Cost(types.Usage{InputTokens: 1000000, CachedInputTokens: 500000})
+	if math.Abs(cost-2) > 1e-9 {
+		t.Errorf("expected $2.00, got $%v", cost)
+	}
+}
+
+func TestParseAndMerge(t *testing.T) {
+	override, err := Parse([]byte(`{"version": 1, "providers": {"Claude": {"my-model": {"input": 1, "output": 2}}}}`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	table := Default()
+	table.Merge(override)
+	if rate, ok := table.Lookup("anthropic", "my-model"); !ok || rate.Output != 2 {
+		t.Errorf("expected the override rate, got %v, %v", rate, ok)
+	}
+	if _, ok := table.Lookup("anthropic", "claude-3-haiku"); !ok {
+		t.Error("merging should keep the built-in rates")
+	}
+
+	if _, err := Parse([]byte("providers: {}")); err == nil {
+		t.Error("expected an error for a file without a version")
+	}
+	if _, err := Parse([]byte("version: 99")); err == nil {
+		t.Error("expected an error for a newer version")
+	}
+}
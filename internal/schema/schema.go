@@ -0,0 +1,78 @@
+// Package schema validates structured agent outputs against JSON Schemas.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Validator checks values against a compiled JSON Schema
+type Validator struct {
+	resolved *jsonschema.Resolved
+}
+
+// Compile prepares a JSON Schema, given as decoded JSON or YAML, for
+// validation. A "$schema" draft declaration is ignored; schemas are read as
+// draft 2020-12, which the common keywords of older drafts agree with.
+func Compile(definition map[string]interface{}) (*Validator, error) {
+	copied := make(map[string]interface{}, len(definition))
+	for k, v := range definition {
+		if k != "$schema" {
+			copied[k] = v
+		}
+	}
+	data, err := json.Marshal(copied)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var s jsonschema.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	resolved, err := s.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Validator{resolved: resolved}, nil
+}
+
+// Validate reports how value breaks the schema, or nil when it matches
+func (v *Validator) Validate(value interface{}) error {
+	return v.resolved.Validate(value)
+}
+
+// Decode extracts the JSON value from a model reply. Models often wrap JSON
+// in a ```json fence or add a sentence around it, so both are tolerated.
+func Decode(reply string) (interface{}, error) {
+	text := strings.TrimSpace(reply)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+	}
+
+	var value interface{}
+	err := json.Unmarshal([]byte(text), &value)
+	if err == nil {
+		return value, nil
+	}
+
+	// Fall back to the outermost object or array in the text
+	start := strings.IndexAny(text, "{[")
+	if start >= 0 {
+		closing := "}"
+		if text[start] == '[' {
+			closing = "]"
+		}
+		if end := strings.LastIndex(text, closing); end > start {
+			if json.Unmarshal([]byte(text[start:end+1]), &value) == nil {
+				return value, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("reply is not valid JSON: %w", err)
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	v, err := Compile(map[string]interface{}{
+		"$schema":  "http://json-schema.org/draft-07/schema#",
+		"type":     "object",
+		"required": []interface{}{"verdict", "score"},
+		"properties": map[string]interface{}{
+			"verdict": map[string]interface{}{"type": "string", "enum": []interface{}{"approve", "reject"}},
+			"score":   map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	valid, _ := Decode(`{"verdict": "approve", "score": 8}`)
+	if err := v.Validate(valid); err != nil {
+		t.Errorf("expected a valid value, got %v", err)
+	}
+	invalid, _ := Decode(`{"verdict": "maybe"}`)
+	if err := v.Validate(invalid); err == nil {
+		t.Error("expected a validation error")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []string{
+		`{"a": 1}`,
+		"```json\n{\"a\": 1}\n```",
+		"Here is the result:\n{\"a\": 1}\nLet me know if you need more.",
+	}
+	for _, reply := range tests {
+		value, err := Decode(reply)
+		if err != nil {
+			t.Errorf("Decode(%q) error: %v", reply, err)
+			continue
+		}
+		if obj, ok := value.(map[string]interface{}); !ok || obj["a"] != 1.0 {
+			t.Errorf("Decode(%q) = %v", reply, value)
+		}
+	}
+
+	if _, err := Decode("no json here"); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("expected a decode error, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/expr-lang/expr"
@@ -21,7 +22,17 @@ func (c *CalcTool) Description() string {
 	return "Evaluate mathematical expressions. Supports +, -, *, /, %, ^, comparisons, and functions like abs(), max(), min(), len()."
 }
 
-func (c *CalcTool) Execute(input string) (string, error) {
+func (c *CalcTool) InputSchema() map[string]interface{} {
+	return objectSchema(map[string]string{
+		"expression": "Expression to evaluate, e.g. (2 + 3) * 4",
+	}, "expression")
+}
+
+func (c *CalcTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	return c.Execute(ctx, stringArg(args, "expression"))
+}
+
+func (c *CalcTool) Execute(ctx context.Context, input string) (string, error) {
 	program, err := expr.Compile(input)
 	if err != nil {
 		return "", fmt.Errorf("expression error: %w", err)
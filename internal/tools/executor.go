@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -10,6 +11,7 @@ import (
 type ToolCall struct {
 	Name  string
 	Input string
+	Args  map[string]interface{} // Structured arguments from a native tool call
 }
 
 // ToolResult represents the result of a tool execution
@@ -23,7 +25,7 @@ type ToolResult struct {
 // Format: ```tool:<name>\n<input>\n```
 func ParseToolCalls(response string) []ToolCall {
 	// Match ```tool:<name>\n...\n```
-	re := regexp.MustCompile("(?s)```tool:([a-zA-Z_][a-zA-Z0-9_]*)\n(.*?)```")
+	re := regexp.MustCompile("(?s)```tool:([a-zA-Z_][a-zA-Z0-9_.-]*)\n(.*?)```")
 	matches := re.FindAllStringSubmatch(response, -1)
 
 	var calls []ToolCall
@@ -38,22 +40,29 @@ func ParseToolCalls(response string) []ToolCall {
 	return calls
 }
 
-// ExecuteToolCalls runs all parsed tool calls and returns results
-func ExecuteToolCalls(calls []ToolCall) []ToolResult {
+// ExecuteToolCalls runs all parsed tool calls and returns results. Only
+// tools in the registry can run; calls to any other tool fail. Calls left
+// when ctx is cancelled are reported with the context error.
+func (r *Registry) ExecuteToolCalls(ctx context.Context, calls []ToolCall) []ToolResult {
 	var results []ToolResult
 
 	for _, call := range calls {
-		tool, ok := Get(call.Name)
+		if err := ctx.Err(); err != nil {
+			results = append(results, ToolResult{ToolName: call.Name, Error: err})
+			continue
+		}
+
+		tool, ok := r.Get(call.Name)
 		if !ok {
 			results = append(results, ToolResult{
 				ToolName: call.Name,
-				Error:    fmt.Errorf("unknown tool: %s", call.Name),
+				Error:    fmt.Errorf("unknown or unavailable tool: %s", call.Name),
 			})
 			continue
 		}
 
 		fmt.Printf("  🔧 Executing tool: %s\n", call.Name)
-		output, err := tool.Execute(call.Input)
+		output, err := executeCall(ctx, tool, call)
 		results = append(results, ToolResult{
 			ToolName: call.Name,
 			Output:   output,
@@ -64,6 +73,17 @@ func ExecuteToolCalls(calls []ToolCall) []ToolResult {
 	return results
 }
 
+// executeCall passes structured arguments to tools that accept them and the
+// input string to all others
+func executeCall(ctx context.Context, tool Tool, call ToolCall) (string, error) {
+	if call.Args != nil {
+		if structured, ok := tool.(StructuredTool); ok {
+			return structured.ExecuteArgs(ctx, call.Args)
+		}
+	}
+	return tool.Execute(ctx, call.Input)
+}
+
 // FormatToolResults creates a string describing tool results for LLM
 func FormatToolResults(results []ToolResult) string {
 	if len(results) == 0 {
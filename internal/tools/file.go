@@ -1,57 +1,229 @@
 package tools
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-// FileTool provides file system operations
-type FileTool struct{}
+// MaxFileResults caps the matches grep and glob return
+const MaxFileResults = 200
+
+// FileTool provides file system operations. Without a policy it can reach
+// any path the process can.
+type FileTool struct {
+	Policy *FilePolicy                // Confines operations when set
+	OnDeny func(input, reason string) // Told about each refused operation when set
+}
 
 func init() {
 	Register(&FileTool{})
 }
 
+// fileRequest is one parsed file operation
+type fileRequest struct {
+	cmd     string
+	path    string
+	content string // Written, appended or applied as a patch
+	pattern string // Regex for grep, glob for glob
+	start   int    // First line to read, from 1
+	end     int    // Last line to read; 0 reads to the end
+}
+
 func (f *FileTool) Name() string {
 	return "file"
 }
 
 func (f *FileTool) Description() string {
-	return "File operations. Commands: 'read:<path>' to read file, 'write:<path>:<content>' to write, 'list:<dir>' to list directory, 'exists:<path>' to check existence."
+	return "File operations. Commands: 'read:<path>' to read a file, 'read:<path>:<start>-<end>' to read lines, " +
+		"'write:<path>:<content>' to write, 'append:<path>:<content>' to append, " +
+		"'patch:<path>:<unified diff>' to apply a diff to a file (or to files under a directory), " +
+		"'grep:<dir>:<regex>' to search file contents, 'glob:<dir>:<pattern>' to find files (** matches any depth), " +
+		"'list:<dir>' to list a directory, 'exists:<path>' to check existence. " +
+		"Content may also start on the line after '<command>:<path>'."
 }
 
-func (f *FileTool) Execute(input string) (string, error) {
+func (f *FileTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"operation": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"read", "write", "append", "patch", "grep", "glob", "list", "exists"},
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory path; the directory to search for grep and glob",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Content to write or append, or the unified diff to apply (write, append and patch only)",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression for grep, or a glob such as **/*.go for glob",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line to read, from 1 (read only)",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last line to read (read only)",
+			},
+		},
+		"required": []string{"operation", "path"},
+	}
+}
+
+func (f *FileTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	req := fileRequest{
+		cmd:     strings.ToLower(stringArg(args, "operation")),
+		path:    stringArg(args, "path"),
+		content: stringArg(args, "content"),
+		pattern: stringArg(args, "pattern"),
+	}
+	req.start, _ = strconv.Atoi(stringArg(args, "start_line"))
+	req.end, _ = strconv.Atoi(stringArg(args, "end_line"))
+	return f.run(req)
+}
+
+func (f *FileTool) Execute(ctx context.Context, input string) (string, error) {
 	input = strings.TrimSpace(input)
 
 	// Parse command
-	parts := strings.SplitN(input, ":", 2)
-	if len(parts) < 2 {
+	cmd, rest, ok := strings.Cut(input, ":")
+	if !ok {
 		return "", fmt.Errorf("invalid format. Use 'read:<path>', 'write:<path>:<content>', 'list:<dir>', or 'exists:<path>'")
 	}
+	req := fileRequest{cmd: strings.ToLower(strings.TrimSpace(cmd))}
 
-	cmd := strings.ToLower(parts[0])
-	arg := parts[1]
+	switch req.cmd {
+	case "write", "append", "patch":
+		if req.path, req.content, ok = cutFileArg(rest); !ok {
+			return "", fmt.Errorf("%s requires path and content: '%s:<path>:<content>'", req.cmd, req.cmd)
+		}
+	case "grep", "glob":
+		if req.path, req.pattern, ok = cutFileArg(rest); !ok {
+			return "", fmt.Errorf("%s requires a directory and pattern: '%s:<dir>:<pattern>'", req.cmd, req.cmd)
+		}
+		req.pattern = strings.TrimSpace(req.pattern)
+	case "read":
+		req.path = strings.TrimSpace(rest)
+		if i := strings.LastIndex(req.path, ":"); i >= 0 {
+			if start, end, ok := parseLineRange(req.path[i+1:]); ok {
+				req.path, req.start, req.end = req.path[:i], start, end
+			}
+		}
+	default:
+		req.path = strings.TrimSpace(rest)
+	}
+	return f.run(req)
+}
+
+// cutFileArg splits "<path>:<arg>" or "<path>\n<arg>" at whichever
+// separator comes first, so content on later lines may contain colons
+func cutFileArg(s string) (path, arg string, ok bool) {
+	i := strings.IndexAny(s, ":\n")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:i]), s[i+1:], true
+}
+
+// parseLineRange parses "10-20", "10-" or "10" into 1-based line numbers
+func parseLineRange(s string) (int, int, bool) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(from)
+	if err != nil || start < 1 {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+	if to == "" {
+		return start, 0, true
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// run checks a parsed file operation against the policy and dispatches it
+func (f *FileTool) run(req fileRequest) (string, error) {
+	size := int64(len(req.content))
+	switch req.cmd {
+	case "append":
+		if info, err := os.Stat(req.path); err == nil {
+			size += info.Size()
+		}
+	case "patch":
+		size = 0 // Checked against each patched file's new content instead
+	}
+	path, err := f.confine(req.cmd, req.path, size)
+	if err != nil {
+		return "", err
+	}
 
-	switch cmd {
+	switch req.cmd {
 	case "read":
-		return f.readFile(arg)
-	case "write":
-		writeParts := strings.SplitN(arg, ":", 2)
-		if len(writeParts) < 2 {
-			return "", fmt.Errorf("write requires path and content: 'write:<path>:<content>'")
+		if req.start > 0 || req.end > 0 {
+			return f.readLines(path, req.start, req.end)
 		}
-		return f.writeFile(writeParts[0], writeParts[1])
+		return f.readFile(path)
+	case "write":
+		return f.writeFile(path, req.content)
+	case "append":
+		return f.appendFile(path, req.content)
+	case "patch":
+		return f.patch(path, req.content)
+	case "grep":
+		return f.grep(path, req.pattern)
+	case "glob":
+		return f.glob(path, req.pattern)
 	case "list":
-		return f.listDir(arg)
+		return f.listDir(path)
 	case "exists":
-		return f.exists(arg)
+		return f.exists(path)
 	default:
-		return "", fmt.Errorf("unknown command: %s. Use read, write, list, or exists", cmd)
+		return "", fmt.Errorf("unknown command: %s. Use read, write, append, patch, grep, glob, list, or exists", req.cmd)
 	}
 }
 
+// confine resolves a path through the policy, if there is one, reporting
+// refusals
+func (f *FileTool) confine(cmd, path string, size int64) (string, error) {
+	if f.Policy == nil {
+		return path, nil
+	}
+	resolved, err := f.Policy.check(cmd, path, size)
+	if err != nil {
+		if f.OnDeny != nil {
+			f.OnDeny(cmd+":"+path, err.Error())
+		}
+		return "", err
+	}
+	return resolved, nil
+}
+
+// visible reports whether a search may look at path. Files the policy
+// refuses are skipped without being reported.
+func (f *FileTool) visible(path string) bool {
+	if f.Policy == nil {
+		return true
+	}
+	_, err := f.Policy.check("read", path, 0)
+	return err == nil
+}
+
 func (f *FileTool) readFile(path string) (string, error) {
 	path = filepath.Clean(path)
 	content, err := os.ReadFile(path)
@@ -61,6 +233,28 @@ func (f *FileTool) readFile(path string) (string, error) {
 	return string(content), nil
 }
 
+// readLines returns lines start to end of a file; end 0 reads to the end
+func (f *FileTool) readLines(path string, start, end int) (string, error) {
+	content, err := f.readFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if start < 1 {
+		start = 1
+	}
+	if start > len(lines) {
+		return "", fmt.Errorf("line %d is past the end of %s (%d lines)", start, path, len(lines))
+	}
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if end < start {
+		return "", fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+	return strings.Join(lines[start-1:end], "\n"), nil
+}
+
 func (f *FileTool) writeFile(path, content string) (string, error) {
 	path = filepath.Clean(path)
 
@@ -76,6 +270,252 @@ func (f *FileTool) writeFile(path, content string) (string, error) {
 	return fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), path), nil
 }
 
+func (f *FileTool) appendFile(path, content string) (string, error) {
+	path = filepath.Clean(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		return "", fmt.Errorf("failed to append to file: %w", err)
+	}
+	return fmt.Sprintf("Successfully appended %d bytes to %s", len(content), path), nil
+}
+
+// patch applies a unified diff to a file, or to the files it names under a
+// directory. Either every file is patched or, on a conflict, none is.
+func (f *FileTool) patch(path, diff string) (string, error) {
+	patches, err := parsePatch(diff)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	inDir := err == nil && info.IsDir()
+	if !inDir && len(patches) > 1 {
+		return "", fmt.Errorf("the diff changes %d files; give the directory they are in as the path", len(patches))
+	}
+
+	type change struct {
+		path    string
+		content string
+		remove  bool
+		summary string
+	}
+	var changes []change
+	var conflicts []string
+	for _, p := range patches {
+		target, name := path, filepath.Base(path)
+		if inDir {
+			name = p.newName
+			if name == devNull {
+				name = p.oldName
+			}
+			if name == "" || name == devNull {
+				return "", fmt.Errorf("the diff doesn't name the file to change; give the file as the path")
+			}
+			if target, err = f.confine("patch", filepath.Join(path, name), 0); err != nil {
+				return "", err
+			}
+		}
+
+		original := ""
+		if p.oldName != devNull {
+			data, err := os.ReadFile(target)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			original = string(data)
+		}
+		patched, err := applyHunks(original, p.hunks)
+		if err != nil {
+			for _, conflict := range strings.Split(err.Error(), "\n") {
+				conflicts = append(conflicts, name+": "+conflict)
+			}
+			continue
+		}
+		if _, err := f.confine("patch", target, int64(len(patched))); err != nil {
+			return "", err
+		}
+
+		added, removed := 0, 0
+		for _, h := range p.hunks {
+			for _, line := range h.lines {
+				switch line[0] {
+				case '+':
+					added++
+				case '-':
+					removed++
+				}
+			}
+		}
+		changes = append(changes, change{
+			path:    target,
+			content: patched,
+			remove:  p.newName == devNull,
+			summary: fmt.Sprintf("%s (+%d -%d)", name, added, removed),
+		})
+	}
+	if len(conflicts) > 0 {
+		return "", fmt.Errorf("%w, nothing was changed:\n%s", ErrPatchConflict, strings.Join(conflicts, "\n"))
+	}
+
+	summaries := make([]string, 0, len(changes))
+	for _, c := range changes {
+		if c.remove {
+			if err := os.Remove(c.path); err != nil {
+				return "", fmt.Errorf("failed to remove %s: %w", c.path, err)
+			}
+		} else {
+			if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+				return "", fmt.Errorf("failed to create directory: %w", err)
+			}
+			// WriteFile keeps the mode of files that already exist
+			if err := os.WriteFile(c.path, []byte(c.content), 0644); err != nil {
+				return "", fmt.Errorf("failed to write %s: %w", c.path, err)
+			}
+		}
+		summaries = append(summaries, c.summary)
+	}
+	return fmt.Sprintf("Successfully patched %s", strings.Join(summaries, ", ")), nil
+}
+
+// grep searches the files under root, or root itself, for lines matching a
+// regular expression. Binary files and .git directories are skipped.
+func (f *FileTool) grep(root, pattern string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("grep requires a pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var matches []string
+	total := 0
+	err = f.walk(root, func(path, rel string, d fs.DirEntry) {
+		if !d.Type().IsRegular() {
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			return
+		}
+		if rel == "." {
+			rel = filepath.Base(path)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			total++
+			if len(matches) < MaxFileResults {
+				if len(line) > 200 {
+					line = line[:200] + "..."
+				}
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", rel, i+1, strings.TrimRight(line, "\r")))
+			}
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search: %w", err)
+	}
+	return formatResults(matches, total, "No matches"), nil
+}
+
+// glob lists the paths under root matching a pattern. '*' and '?' match
+// within a path element and '**' matches any number of elements.
+func (f *FileTool) glob(root, pattern string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("glob requires a pattern")
+	}
+	patternParts := strings.Split(filepath.ToSlash(strings.TrimPrefix(pattern, "./")), "/")
+	if _, err := filepath.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var matches []string
+	total := 0
+	err := f.walk(root, func(path, rel string, d fs.DirEntry) {
+		if rel == "." || !matchGlob(patternParts, strings.Split(filepath.ToSlash(rel), "/")) {
+			return
+		}
+		total++
+		if len(matches) < MaxFileResults {
+			if d.IsDir() {
+				rel += "/"
+			}
+			matches = append(matches, rel)
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search: %w", err)
+	}
+	return formatResults(matches, total, "No files match"), nil
+}
+
+// walk visits what lies under root that the policy lets searches see,
+// skipping .git directories. rel is the path relative to root.
+func (f *FileTool) walk(root string, visit func(path, rel string, d fs.DirEntry)) error {
+	root = filepath.Clean(root)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path != root && (!f.visible(path) || (d.IsDir() && d.Name() == ".git")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		visit(path, rel, d)
+		return nil
+	})
+}
+
+// matchGlob matches path elements against pattern elements, where "**"
+// stands for any number of elements
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// formatResults lists search results, noting any over MaxFileResults
+func formatResults(results []string, total int, none string) string {
+	if total == 0 {
+		return none
+	}
+	out := strings.Join(results, "\n")
+	if total > len(results) {
+		out += fmt.Sprintf("\n... [%d more not shown]", total-len(results))
+	}
+	return out
+}
+
 func (f *FileTool) listDir(path string) (string, error) {
 	path = filepath.Clean(path)
 	entries, err := os.ReadDir(path)
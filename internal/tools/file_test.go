@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestFileToolOperations(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "pkg", "sub"), 0755)
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg\n// TODO: a\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "sub", "b.go"), []byte("package sub\n// TODO: b\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "blob.bin"), []byte("TODO\x00"), 0644)
+	os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("TODO"), 0644)
+	file := &FileTool{}
+	ctx := context.Background()
+
+	out, err := file.Execute(ctx, "read:"+filepath.Join(root, "main.go")+":3-4")
+	if err != nil || out != "func main() {\n\tprintln(\"hi\")" {
+		t.Errorf("expected lines 3-4, got %q, %v", out, err)
+	}
+	out, err = file.ExecuteArgs(ctx, map[string]interface{}{"operation": "read", "path": filepath.Join(root, "main.go"), "start_line": float64(5)})
+	if err != nil || out != "}" {
+		t.Errorf("expected the last line, got %q, %v", out, err)
+	}
+	if _, err := file.Execute(ctx, "read:"+filepath.Join(root, "main.go")+":9-"); err == nil {
+		t.Error("expected a start past the end to fail")
+	}
+
+	out, err = file.Execute(ctx, "grep:"+root+":TODO: \\w")
+	if err != nil || out != "pkg/a.go:2: // TODO: a\npkg/sub/b.go:2: // TODO: b" {
+		t.Errorf("expected matches outside .git and binaries, got %q, %v", out, err)
+	}
+	if out, _ := file.Execute(ctx, "grep:"+root+":nothing here"); out != "No matches" {
+		t.Errorf("expected no matches, got %q", out)
+	}
+
+	out, err = file.Execute(ctx, "glob:"+root+":**/*.go")
+	if err != nil || out != "main.go\npkg/a.go\npkg/sub/b.go" {
+		t.Errorf("expected every .go file, got %q, %v", out, err)
+	}
+	if out, _ := file.Execute(ctx, "glob:"+root+":pkg/*"); out != "pkg/a.go\npkg/blob.bin\npkg/sub/" {
+		t.Errorf("expected pkg's entries, got %q", out)
+	}
+
+	log := filepath.Join(root, "logs", "run.log")
+	file.ExecuteArgs(ctx, map[string]interface{}{"operation": "append", "path": log, "content": "first: line\n"})
+	file.Execute(ctx, "append:"+log+"\nsecond")
+	if data, _ := os.ReadFile(log); string(data) != "first: line\nsecond" {
+		t.Errorf("expected both appends, got %q", data)
+	}
+
+	// Content on the following lines may contain colons
+	notes := filepath.Join(root, "notes.txt")
+	if _, err := file.Execute(ctx, "write:"+notes+"\nkey: value\nurl: http://x"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "key: value\nurl: http://x" {
+		t.Errorf("expected the newline form to keep colons, got %q", data)
+	}
+}
+
+func TestFileToolPatch(t *testing.T) {
+	root := t.TempDir()
+	mainPath := filepath.Join(root, "main.go")
+	original := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+	os.WriteFile(mainPath, []byte(original), 0644)
+	file := &FileTool{}
+	ctx := context.Background()
+
+	// Line numbers are off by two, as if written against an older version
+	diff := `--- a/main.go
++++ b/main.go
+@@ -7,3 +7,4 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
++	fmt.Println("bye")
+ }
+`
+	out, err := file.Execute(ctx, "patch:"+mainPath+"\n"+diff)
+	if err != nil || !strings.Contains(out, "main.go (+2 -1)") {
+		t.Fatalf("expected the patch to apply, got %q, %v", out, err)
+	}
+	want := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n\tfmt.Println(\"bye\")\n}\n"
+	if data, _ := os.ReadFile(mainPath); string(data) != want {
+		t.Errorf("unexpected patched file:\n%s", data)
+	}
+
+	// A multi-file diff against a directory: one hunk conflicts, so nothing changes
+	os.WriteFile(filepath.Join(root, "other.go"), []byte("package main\n\nvar x = 1\n"), 0644)
+	multi := `--- a/other.go
++++ b/other.go
+@@ -3 +3 @@
+-var x = 1
++var x = 2
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,2 @@
++package main
++// new
+--- a/main.go
++++ b/main.go
+@@ -6,1 +6,1 @@
+-	fmt.Println("goodbye")
++	fmt.Println("farewell")
+`
+	_, err = file.ExecuteArgs(ctx, map[string]interface{}{"operation": "patch", "path": root, "content": multi})
+	if !errors.Is(err, ErrPatchConflict) || !strings.Contains(err.Error(), `main.go: hunk 1 (@@ -6,1 +6,1 @@): expected "\tfmt.Println(\"goodbye\")" at line 6`) {
+		t.Errorf("expected a conflict report, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "other.go")); !strings.Contains(string(data), "x = 1") {
+		t.Error("expected no file to change when a hunk conflicts")
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Error("expected new.go not to be created when a hunk conflicts")
+	}
+
+	multi = multi[:strings.LastIndex(multi, "--- a/main.go")]
+	out, err = file.ExecuteArgs(ctx, map[string]interface{}{"operation": "patch", "path": root, "content": multi})
+	if err != nil || out != "Successfully patched other.go (+1 -1), new.go (+2 -0)" {
+		t.Fatalf("expected both files to be patched, got %q, %v", out, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "new.go")); string(data) != "package main\n// new\n" {
+		t.Errorf("unexpected new file %q", data)
+	}
+
+	readOnly := &FileTool{Policy: NewFilePolicy(types.FileAccessConfig{Roots: []string{root}})}
+	for _, input := range []string{"patch:" + mainPath + "\n" + diff, "append:" + mainPath + ":x"} {
+		if _, err := readOnly.Execute(ctx, input); !errors.Is(err, ErrFileAccessDenied) {
+			t.Errorf("%s: expected read-only access to refuse it, got %v", strings.SplitN(input, ":", 2)[0], err)
+		}
+	}
+	escape := "--- a/../outside.go\n+++ b/../outside.go\n@@ -0,0 +1 @@\n+package x\n"
+	writable := &FileTool{Policy: NewFilePolicy(types.FileAccessConfig{Roots: []string{root}, Mode: types.FileAccessReadWrite})}
+	if _, err := writable.Execute(ctx, "patch:"+root+"\n"+escape); !errors.Is(err, ErrFileAccessDenied) {
+		t.Errorf("expected a diff naming a file outside the root to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// HTTP tool defaults, used for settings a workflow leaves unset
+const (
+	DefaultHTTPTimeout     = 30 * time.Second
+	DefaultHTTPMaxResponse = 65536
+)
+
+// ErrHostNotAllowed is wrapped by every error for a URL outside the http
+// allow-list
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// HTTPTool sends GET and POST requests to allow-listed hosts. The
+// registered instance has no hosts and refuses everything; workflows with
+// an http section get their own instance.
+type HTTPTool struct {
+	allowHosts  []string
+	headers     []types.HTTPHeaderConfig
+	maxResponse int
+	client      *http.Client
+
+	OnDeny func(input, reason string) // Told about each refused request when set
+}
+
+func init() {
+	Register(&HTTPTool{})
+}
+
+// NewHTTPTool builds the http tool a workflow's http section describes
+func NewHTTPTool(config types.HTTPConfig) *HTTPTool {
+	tool := &HTTPTool{
+		allowHosts:  config.AllowHosts,
+		headers:     config.Headers,
+		maxResponse: config.MaxResponse,
+	}
+	if tool.maxResponse <= 0 {
+		tool.maxResponse = DefaultHTTPMaxResponse
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	tool.client = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !tool.allowed(req.URL) {
+				return fmt.Errorf("%w: redirect to %s", ErrHostNotAllowed, req.URL.Host)
+			}
+			// Injected credentials only go to the hosts they are configured for
+			for _, header := range tool.headers {
+				req.Header.Del(header.Name)
+			}
+			tool.inject(req)
+			return nil
+		},
+	}
+	return tool
+}
+
+func (h *HTTPTool) Name() string {
+	return "http"
+}
+
+func (h *HTTPTool) Description() string {
+	hosts := "none"
+	if len(h.allowHosts) > 0 {
+		hosts = strings.Join(h.allowHosts, ", ")
+	}
+	return fmt.Sprintf("Send an HTTP GET or POST request. Allowed hosts: %s. "+
+		`Input is JSON: {"method": "GET", "url": "...", "headers": {...}, "body": {...}, "extract": "$.items[*].id"}, `+
+		"or 'GET <url>'. extract is an optional JSONPath applied to a JSON response.", hosts)
+}
+
+func (h *HTTPTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"method": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"GET", "POST"},
+			},
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Full URL including the scheme",
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Extra request headers",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"body": map[string]interface{}{
+				"description": "Request body for POST; objects and arrays are sent as JSON",
+			},
+			"extract": map[string]interface{}{
+				"type":        "string",
+				"description": "JSONPath selecting part of a JSON response, e.g. $.data[0].name (optional)",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// httpRequest is a request as the model describes it
+type httpRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    interface{}       `json:"body"`
+	Extract string            `json:"extract"`
+}
+
+func (h *HTTPTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	var req httpRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return "", fmt.Errorf("invalid http arguments: %w", err)
+	}
+	return h.do(ctx, req)
+}
+
+// Execute takes a JSON request, or "METHOD URL" with an optional body on
+// the following lines
+func (h *HTTPTool) Execute(ctx context.Context, input string) (string, error) {
+	input = strings.TrimSpace(input)
+	var req httpRequest
+	if strings.HasPrefix(input, "{") {
+		if err := json.Unmarshal([]byte(input), &req); err != nil {
+			return "", fmt.Errorf("invalid http request: %w", err)
+		}
+		return h.do(ctx, req)
+	}
+
+	line, body, _ := strings.Cut(input, "\n")
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 1:
+		req.URL = fields[0]
+	case 2:
+		req.Method, req.URL = fields[0], fields[1]
+	default:
+		return "", fmt.Errorf("invalid format. Use 'GET <url>', 'POST <url>' followed by a body, or a JSON request")
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		req.Body = body
+	}
+	return h.do(ctx, req)
+}
+
+// do sends a request and shapes the response for the model
+func (h *HTTPTool) do(ctx context.Context, req httpRequest) (string, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		return "", fmt.Errorf("unsupported method %s (use GET or POST)", method)
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", fmt.Errorf("invalid url %q: an absolute http or https URL is required", req.URL)
+	}
+	if !h.allowed(target) {
+		err := fmt.Errorf("%w: %s", ErrHostNotAllowed, target.Host)
+		if h.OnDeny != nil {
+			h.OnDeny(method+" "+req.URL, err.Error())
+		}
+		return "", err
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := req.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+		if json.Valid([]byte(b)) {
+			contentType = "application/json"
+		}
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return "", fmt.Errorf("invalid body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return "", err
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+	h.inject(httpReq)
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		if errors.Is(err, ErrHostNotAllowed) && h.OnDeny != nil {
+			h.OnDeny(method+" "+req.URL, err.Error())
+		}
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(h.maxResponse)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	truncated := len(data) > h.maxResponse
+	if truncated {
+		data = data[:h.maxResponse]
+	}
+
+	status := fmt.Sprintf("HTTP %s", resp.Status)
+	if req.Extract == "" {
+		if truncated {
+			return fmt.Sprintf("%s\n%s\n... [truncated at %d bytes]", status, data, h.maxResponse), nil
+		}
+		return fmt.Sprintf("%s\n%s", status, data), nil
+	}
+
+	if truncated {
+		return "", fmt.Errorf("response is larger than max_response (%d bytes), so extract can't parse it", h.maxResponse)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return "", fmt.Errorf("%s: response is not JSON, so extract can't be applied", status)
+	}
+	extracted, err := extractJSONPath(decoded, req.Extract)
+	if err != nil {
+		return "", err
+	}
+	result, _ := json.Marshal(extracted)
+	return fmt.Sprintf("%s\n%s", status, result), nil
+}
+
+// allowed reports whether the URL's host is on the allow-list
+func (h *HTTPTool) allowed(target *url.URL) bool {
+	for _, pattern := range h.allowHosts {
+		if hostMatches(pattern, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// inject adds the configured headers meant for the request's host,
+// overriding any the model set
+func (h *HTTPTool) inject(req *http.Request) {
+	for _, header := range h.headers {
+		if header.Host != "" && !hostMatches(header.Host, req.URL) {
+			continue
+		}
+		if value, ok := os.LookupEnv(header.Env); ok {
+			req.Header.Set(header.Name, header.Prefix+value)
+		}
+	}
+}
+
+// hostMatches matches a URL against a host pattern. Patterns with a port
+// match that port only; "*.example.com" matches subdomains, not
+// example.com itself.
+func hostMatches(pattern string, target *url.URL) bool {
+	pattern = strings.ToLower(pattern)
+	host := strings.ToLower(target.Hostname())
+	if strings.Contains(pattern, ":") && !strings.HasPrefix(pattern, "[") {
+		host = strings.ToLower(target.Host)
+		if target.Port() == "" {
+			return false
+		}
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestHTTPTool(t *testing.T) {
+	t.Setenv("ORKA_HTTP_TOKEN", "s3cret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}], "auth": "` + r.Header.Get("Authorization") + `"}`))
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 300)))
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	var denials []string
+	tool := NewHTTPTool(types.HTTPConfig{
+		AllowHosts:  []string{host},
+		Headers:     []types.HTTPHeaderConfig{{Name: "Authorization", Env: "ORKA_HTTP_TOKEN", Prefix: "Bearer "}},
+		MaxResponse: 256,
+	})
+	tool.OnDeny = func(input, reason string) { denials = append(denials, input) }
+
+	out, err := tool.ExecuteArgs(context.Background(), map[string]interface{}{
+		"url":     server.URL + "/items",
+		"headers": map[string]interface{}{"Authorization": "Bearer forged"},
+		"extract": "$.auth",
+	})
+	if err != nil || out != `HTTP 200 OK`+"\n"+`"Bearer s3cret"` {
+		t.Errorf("expected the injected header to win, got %q, %v", out, err)
+	}
+
+	out, err = tool.ExecuteArgs(context.Background(), map[string]interface{}{
+		"url":     server.URL + "/items",
+		"extract": "$.items[*].name",
+	})
+	if err != nil || !strings.HasSuffix(out, `["a","b"]`) {
+		t.Errorf("expected extracted names, got %q, %v", out, err)
+	}
+
+	out, err = tool.ExecuteArgs(context.Background(), map[string]interface{}{
+		"method": "POST",
+		"url":    server.URL + "/echo",
+		"body":   map[string]interface{}{"q": "x"},
+	})
+	if err != nil || out != "HTTP 200 OK\n"+`POST application/json {"q":"x"}` {
+		t.Errorf("expected a JSON body to be posted, got %q, %v", out, err)
+	}
+
+	out, err = tool.Execute(context.Background(), "POST "+server.URL+"/echo\nplain text")
+	if err != nil || !strings.HasSuffix(out, "POST  plain text") {
+		t.Errorf("expected the fence format to post its body, got %q, %v", out, err)
+	}
+
+	out, err = tool.Execute(context.Background(), "GET "+server.URL+"/large")
+	if err != nil || !strings.Contains(out, "[truncated at 256 bytes]") {
+		t.Errorf("expected the response to be truncated, got %q, %v", out, err)
+	}
+
+	denied := []string{
+		"GET http://example.com/",
+		"GET http://127.0.0.1:1/",
+		"GET file:///etc/passwd",
+	}
+	for _, input := range denied {
+		if _, err := tool.Execute(context.Background(), input); err == nil {
+			t.Errorf("%s: expected the request to be refused", input)
+		}
+	}
+	if len(denials) != 2 {
+		t.Errorf("expected the two disallowed hosts to be reported, got %v", denials)
+	}
+	if _, err := tool.Execute(context.Background(), "DELETE "+server.URL+"/items"); err == nil {
+		t.Errorf("expected DELETE to be refused")
+	}
+}
+
+func TestHTTPToolRedirects(t *testing.T) {
+	t.Setenv("ORKA_HTTP_TOKEN", "s3cret")
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("auth=" + r.Header.Get("X-Token")))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+	serverHost, _ := url.Parse(server.URL)
+	otherHost, _ := url.Parse(other.URL)
+
+	strict := NewHTTPTool(types.HTTPConfig{AllowHosts: []string{serverHost.Host}})
+	if _, err := strict.Execute(context.Background(), server.URL+"/x"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("expected a redirect to another host to be refused, got %v", err)
+	}
+
+	tool := NewHTTPTool(types.HTTPConfig{
+		AllowHosts: []string{serverHost.Host, otherHost.Host},
+		Headers:    []types.HTTPHeaderConfig{{Host: serverHost.Host, Name: "X-Token", Env: "ORKA_HTTP_TOKEN"}},
+	})
+	out, err := tool.Execute(context.Background(), server.URL+"/x")
+	if err != nil || out != "HTTP 200 OK\nauth=" {
+		t.Errorf("expected the header to stay with its host, got %q, %v", out, err)
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern, url string
+		want         bool
+	}{
+		{"api.example.com", "https://api.example.com/v1", true},
+		{"api.example.com", "https://API.example.com:8443/", true},
+		{"api.example.com", "https://example.com/", false},
+		{"*.example.com", "https://api.example.com/", true},
+		{"*.example.com", "https://example.com/", false},
+		{"*.example.com", "https://evilexample.com/", false},
+		{"localhost:8080", "http://localhost:8080/", true},
+		{"localhost:8080", "http://localhost:9090/", false},
+		{"localhost:8080", "http://localhost/", false},
+	}
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.url)
+		if got := hostMatches(tt.pattern, target); got != tt.want {
+			t.Errorf("hostMatches(%q, %q) = %v, want %v", tt.pattern, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestExtractJSONPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "tags": ["x"]}, {"id": 2, "tags": []}], "odd key": true}}`), &doc)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.data.items[0].id", `1`},
+		{"$.data.items[-1].id", `2`},
+		{"$.data['odd key']", `true`},
+		{"$.data.items[*].id", `[1,2]`},
+		{"$.data.items[*].tags[0]", `["x"]`},
+		{"$.data.missing[*]", `[]`},
+		{"$.data.*", `[[{"id":1,"tags":["x"]},{"id":2,"tags":[]}],true]`}, // Fields in key order
+	}
+	for _, tt := range tests {
+		got, err := extractJSONPath(doc, tt.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.path, err)
+			continue
+		}
+		if data, _ := json.Marshal(got); string(data) != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, data, tt.want)
+		}
+	}
+
+	for _, path := range []string{"data", "$.data.missing", "$..id", "$.data.items[x]", "$.data.items[0"} {
+		if _, err := extractJSONPath(doc, path); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extractJSONPath selects values from decoded JSON with a JSONPath
+// expression. The common subset is supported: $ for the root, .name and
+// ['name'] for fields, [n] for array indexes (negative counts from the
+// end), and * or [*] for every element, taking object fields in key order.
+// Paths with a wildcard return a list of matches.
+func extractJSONPath(value interface{}, path string) (interface{}, error) {
+	steps, wildcard, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{value}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range current {
+			matches, err := step.apply(v)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %s: %w", path, err)
+			}
+			next = append(next, matches...)
+		}
+		current = next
+	}
+
+	if wildcard {
+		if current == nil {
+			current = []interface{}{}
+		}
+		return current, nil
+	}
+	if len(current) == 0 {
+		return nil, fmt.Errorf("jsonpath %s matched nothing", path)
+	}
+	return current[0], nil
+}
+
+// jsonPathStep is one segment of a parsed path
+type jsonPathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// apply returns what the step selects from v
+func (s jsonPathStep) apply(v interface{}) ([]interface{}, error) {
+	switch {
+	case s.wildcard:
+		switch v := v.(type) {
+		case []interface{}:
+			return v, nil
+		case map[string]interface{}:
+			// Sorted by key, so the result is the same every run
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			result := make([]interface{}, 0, len(v))
+			for _, key := range keys {
+				result = append(result, v[key])
+			}
+			return result, nil
+		}
+		return nil, nil
+	case s.isIndex:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("[%d] applied to a non-array", s.index)
+		}
+		i := s.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil, nil
+		}
+		return []interface{}{list[i]}, nil
+	default:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		item, ok := obj[s.field]
+		if !ok {
+			return nil, nil
+		}
+		return []interface{}{item}, nil
+	}
+}
+
+// parseJSONPath splits a path into steps, reporting whether any is a
+// wildcard
+func parseJSONPath(path string) ([]jsonPathStep, bool, error) {
+	rest := strings.TrimSpace(path)
+	if !strings.HasPrefix(rest, "$") {
+		return nil, false, fmt.Errorf("jsonpath must start with $: %s", path)
+	}
+	rest = rest[1:]
+
+	var steps []jsonPathStep
+	wildcard := false
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, false, fmt.Errorf("recursive descent (..) is not supported: %s", path)
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "" {
+				return nil, false, fmt.Errorf("empty field name in jsonpath: %s", path)
+			}
+			if name == "*" {
+				steps = append(steps, jsonPathStep{wildcard: true})
+				wildcard = true
+				continue
+			}
+			steps = append(steps, jsonPathStep{field: name})
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, false, fmt.Errorf("unclosed [ in jsonpath: %s", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+				wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{field: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, false, fmt.Errorf("unsupported selector [%s] in jsonpath: %s", inner, path)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, false, fmt.Errorf("unexpected %q in jsonpath: %s", rest[:1], path)
+		}
+	}
+	return steps, wildcard, nil
+}
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrPatchConflict is wrapped by the error for a patch whose hunks don't
+// match the files they change
+var ErrPatchConflict = errors.New("patch does not apply")
+
+// devNull names the missing side of a diff that creates or deletes a file
+const devNull = "/dev/null"
+
+// filePatch is the part of a unified diff that changes one file
+type filePatch struct {
+	oldName string
+	newName string
+	hunks   []hunk
+}
+
+// hunk is one @@ section of a unified diff. Lines keep their ' ', '-' or
+// '+' prefix.
+type hunk struct {
+	header   string
+	oldStart int
+	oldCount int
+	lines    []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch splits a unified diff into the changes it makes to each file.
+// A diff of bare hunks without ---/+++ headers is a change to one file.
+func parsePatch(diff string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	var patches []filePatch
+	current := func() *filePatch {
+		if len(patches) == 0 {
+			patches = append(patches, filePatch{})
+		}
+		return &patches[len(patches)-1]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			patches = append(patches, filePatch{
+				oldName: patchFileName(line[4:]),
+				newName: patchFileName(lines[i+1][4:]),
+			})
+			i++
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed hunk header: %s", line)
+			}
+			h := hunk{header: strings.TrimSpace(line), oldStart: atoiOr(m[1], 0), oldCount: atoiOr(m[2], 1)}
+			newCount := atoiOr(m[4], 1)
+
+			oldSeen, newSeen := 0, 0
+			for oldSeen < h.oldCount || newSeen < newCount {
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("hunk %s ends early", h.header)
+				}
+				body := lines[i]
+				if body == "" {
+					body = " " // Editors strip the space from blank context lines
+				}
+				switch body[0] {
+				case ' ':
+					oldSeen++
+					newSeen++
+				case '-':
+					oldSeen++
+				case '+':
+					newSeen++
+				case '\\':
+					continue // "\ No newline at end of file"
+				default:
+					return nil, fmt.Errorf("hunk %s has an unexpected line: %s", h.header, lines[i])
+				}
+				h.lines = append(h.lines, body)
+			}
+			if oldSeen != h.oldCount || newSeen != newCount {
+				return nil, fmt.Errorf("hunk %s doesn't match its line counts", h.header)
+			}
+			p := current()
+			p.hunks = append(p.hunks, h)
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no hunks found; expected a unified diff with @@ sections")
+	}
+	for _, p := range patches {
+		if len(p.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks found for %s", p.newName)
+		}
+	}
+	return patches, nil
+}
+
+// patchFileName strips the timestamp and a/ or b/ prefix from a ---/+++ name
+func patchFileName(name string) string {
+	name, _, _ = strings.Cut(name, "\t")
+	name = strings.TrimSpace(name)
+	if name == devNull {
+		return name
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		name = name[2:]
+	}
+	return name
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// applyHunks applies hunks to content. A hunk whose context has moved is
+// applied where its lines are found nearest the position it names. Hunks
+// that can't be placed are all reported, one per line, and nothing is
+// applied.
+func applyHunks(content string, hunks []hunk) (string, error) {
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var result, conflicts []string
+	cursor, offset := 0, 0
+	for n, h := range hunks {
+		var old, replacement []string
+		for _, line := range h.lines {
+			if line[0] != '+' {
+				old = append(old, line[1:])
+			}
+			if line[0] != '-' {
+				replacement = append(replacement, line[1:])
+			}
+		}
+
+		want := h.oldStart - 1 + offset
+		if h.oldCount == 0 {
+			want = h.oldStart + offset // Pure insertions name the line they follow
+		}
+		at, ok := findLines(lines, old, want, cursor)
+		if !ok {
+			conflicts = append(conflicts, fmt.Sprintf("hunk %d (%s): %s", n+1, h.header, describeMismatch(lines, old, want)))
+			continue
+		}
+		result = append(result, lines[cursor:at]...)
+		result = append(result, replacement...)
+		cursor = at + len(old)
+		offset = at - (want - offset)
+	}
+	if len(conflicts) > 0 {
+		return "", errors.New(strings.Join(conflicts, "\n"))
+	}
+
+	result = append(result, lines[cursor:]...)
+	if len(result) == 0 {
+		return "", nil
+	}
+	patched := strings.Join(result, "\n")
+	if trailingNewline {
+		patched += "\n"
+	}
+	return patched, nil
+}
+
+// findLines returns the position at or after from where want matches,
+// searching outward from the position near
+func findLines(lines, want []string, near, from int) (int, bool) {
+	if near < from {
+		near = from
+	}
+	last := len(lines) - len(want)
+	for distance := 0; near-distance >= from || near+distance <= last; distance++ {
+		for _, at := range []int{near - distance, near + distance} {
+			if at >= from && at <= last && linesEqual(lines[at:at+len(want)], want) {
+				return at, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func linesEqual(a, b []string) bool {
+	for i := range b {
+		if strings.TrimRight(a[i], "\r") != strings.TrimRight(b[i], "\r") {
+			return false
+		}
+	}
+	return true
+}
+
+// describeMismatch explains why a hunk's lines aren't at the position it names
+func describeMismatch(lines, want []string, at int) string {
+	at = max(at, 0)
+	for i, line := range want {
+		if at+i >= len(lines) {
+			return fmt.Sprintf("expected %q at line %d, but the file has %d lines", line, at+i+1, len(lines))
+		}
+		if lines[at+i] != line {
+			return fmt.Sprintf("expected %q at line %d, found %q", line, at+i+1, lines[at+i])
+		}
+	}
+	return "its context was already consumed by an earlier hunk"
+}
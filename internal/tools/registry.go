@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -9,52 +10,75 @@ import (
 type Tool interface {
 	Name() string
 	Description() string
-	Execute(input string) (string, error)
+	InputSchema() map[string]interface{} // JSON Schema of the arguments for native tool calls
+	Execute(ctx context.Context, input string) (string, error)
 }
 
-// Registry holds all available tools
+// StructuredTool is implemented by tools that can take the decoded arguments
+// of a native tool call instead of a single input string
+type StructuredTool interface {
+	Tool
+	ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error)
+}
+
+// Registry holds a set of tools by name. The package-level functions use
+// the global registry of built-in tools; each workflow run gets its own
+// registry so MCP tools stay with the run that connected them.
 type Registry struct {
 	mu    sync.RWMutex
 	tools map[string]Tool
 }
 
-// Global registry
-var globalRegistry = &Registry{
-	tools: make(map[string]Tool),
+// Global registry of built-in tools, filled by init functions
+var globalRegistry = NewRegistry()
+
+// NewRegistry returns a registry holding the given tools
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool, len(tools))}
+	for _, tool := range tools {
+		r.tools[tool.Name()] = tool
+	}
+	return r
 }
 
-// Register adds a tool to the global registry
-func Register(tool Tool) {
-	globalRegistry.mu.Lock()
-	defer globalRegistry.mu.Unlock()
-	globalRegistry.tools[tool.Name()] = tool
+// NewWorkflowRegistry returns a registry for one workflow run, starting
+// with the built-in tools
+func NewWorkflowRegistry() *Registry {
+	return NewRegistry(GetAll()...)
+}
+
+// Register adds a tool to the registry, replacing one with the same name
+func (r *Registry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[tool.Name()] = tool
 }
 
 // Get retrieves a tool by name
-func Get(name string) (Tool, bool) {
-	globalRegistry.mu.RLock()
-	defer globalRegistry.mu.RUnlock()
-	tool, ok := globalRegistry.tools[name]
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
 	return tool, ok
 }
 
-// GetAll returns all registered tools
-func GetAll() []Tool {
-	globalRegistry.mu.RLock()
-	defer globalRegistry.mu.RUnlock()
+// GetAll returns all tools in the registry
+func (r *Registry) GetAll() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	result := make([]Tool, 0, len(globalRegistry.tools))
-	for _, tool := range globalRegistry.tools {
+	result := make([]Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
 		result = append(result, tool)
 	}
 	return result
 }
 
 // GetByNames returns tools matching the given names
-func GetByNames(names []string) ([]Tool, error) {
+func (r *Registry) GetByNames(names []string) ([]Tool, error) {
 	result := make([]Tool, 0, len(names))
 	for _, name := range names {
-		tool, ok := Get(name)
+		tool, ok := r.Get(name)
 		if !ok {
 			return nil, fmt.Errorf("tool not found: %s", name)
 		}
@@ -64,12 +88,12 @@ func GetByNames(names []string) ([]Tool, error) {
 }
 
 // GetByPrefix returns tools that start with the given prefix
-func GetByPrefix(prefix string) []Tool {
-	globalRegistry.mu.RLock()
-	defer globalRegistry.mu.RUnlock()
+func (r *Registry) GetByPrefix(prefix string) []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
 	result := make([]Tool, 0)
-	for name, tool := range globalRegistry.tools {
+	for name, tool := range r.tools {
 		// Simple prefix match, e.g. "filesystem." matches "filesystem.list"
 		if len(name) > len(prefix) && name[:len(prefix)] == prefix {
 			result = append(result, tool)
@@ -79,17 +103,47 @@ func GetByPrefix(prefix string) []Tool {
 }
 
 // ListNames returns all tool names
-func ListNames() []string {
-	globalRegistry.mu.RLock()
-	defer globalRegistry.mu.RUnlock()
+func (r *Registry) ListNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	names := make([]string, 0, len(globalRegistry.tools))
-	for name := range globalRegistry.tools {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
 		names = append(names, name)
 	}
 	return names
 }
 
+// Register adds a built-in tool to the global registry
+func Register(tool Tool) {
+	globalRegistry.Register(tool)
+}
+
+// Get retrieves a built-in tool by name
+func Get(name string) (Tool, bool) {
+	return globalRegistry.Get(name)
+}
+
+// GetAll returns all built-in tools
+func GetAll() []Tool {
+	return globalRegistry.GetAll()
+}
+
+// GetByNames returns built-in tools matching the given names
+func GetByNames(names []string) ([]Tool, error) {
+	return globalRegistry.GetByNames(names)
+}
+
+// GetByPrefix returns built-in tools that start with the given prefix
+func GetByPrefix(prefix string) []Tool {
+	return globalRegistry.GetByPrefix(prefix)
+}
+
+// ListNames returns all built-in tool names
+func ListNames() []string {
+	return globalRegistry.ListNames()
+}
+
 // FormatToolsForPrompt creates a description of available tools for the LLM
 func FormatToolsForPrompt(tools []Tool) string {
 	if len(tools) == 0 {
@@ -105,3 +159,32 @@ func FormatToolsForPrompt(tools []Tool) string {
 	result += "\nThe tool output will be provided to you for further processing.\n"
 	return result
 }
+
+// objectSchema builds a JSON Schema for an object whose properties are all
+// strings, described by the given map
+func objectSchema(properties map[string]string, required ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(properties))
+	for name, description := range properties {
+		props[name] = map[string]interface{}{
+			"type":        "string",
+			"description": description,
+		}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
+// stringArg reads a string argument, formatting other JSON values
+func stringArg(args map[string]interface{}, key string) string {
+	value, ok := args[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
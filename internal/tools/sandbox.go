@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"Orkflow/pkg/types"
+)
+
+// ErrFileAccessDenied is wrapped by every error for a path the file
+// policy refuses
+var ErrFileAccessDenied = errors.New("file access denied")
+
+// FilePolicy confines file operations to a set of root directories
+type FilePolicy struct {
+	roots       []string // Absolute, symlink-free
+	writable    bool
+	maxFileSize int64
+	deny        []string
+}
+
+// NewFilePolicy builds the policy described by a workflow's file_access
+// section. Roots are resolved once, so later changes to the working
+// directory don't move them.
+func NewFilePolicy(config types.FileAccessConfig) *FilePolicy {
+	policy := &FilePolicy{
+		writable:    config.Mode == types.FileAccessReadWrite,
+		maxFileSize: config.MaxFileSize,
+		deny:        config.Deny,
+	}
+	for _, root := range config.Roots {
+		policy.roots = append(policy.roots, realPath(root))
+	}
+	return policy
+}
+
+// check resolves the path of a file operation and reports whether the
+// policy allows it. size is the number of bytes a write, append or patch
+// would leave in the file.
+func (p *FilePolicy) check(cmd, path string, size int64) (string, error) {
+	resolved := realPath(path)
+
+	root, ok := p.rootOf(resolved)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is outside the allowed roots", ErrFileAccessDenied, path)
+	}
+	if pattern, ok := p.denied(root, resolved); ok {
+		return "", fmt.Errorf("%w: %s matches deny pattern %q", ErrFileAccessDenied, path, pattern)
+	}
+
+	switch cmd {
+	case "write", "append", "patch":
+		if !p.writable {
+			return "", fmt.Errorf("%w: file access is read-only", ErrFileAccessDenied)
+		}
+		if p.maxFileSize > 0 && size > p.maxFileSize {
+			return "", fmt.Errorf("%w: writing %d bytes exceeds max_file_size (%d)", ErrFileAccessDenied, size, p.maxFileSize)
+		}
+	case "read":
+		if info, err := os.Stat(resolved); err == nil && p.maxFileSize > 0 && info.Size() > p.maxFileSize {
+			return "", fmt.Errorf("%w: %s is %d bytes, over max_file_size (%d)", ErrFileAccessDenied, path, info.Size(), p.maxFileSize)
+		}
+	}
+	return resolved, nil
+}
+
+// rootOf returns the allowed root containing path
+func (p *FilePolicy) rootOf(path string) (string, bool) {
+	for _, root := range p.roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// denied returns the deny pattern path matches, if any. Patterns with a
+// slash match the path relative to its root, or a directory above it;
+// others match any single path element, so ".ssh" covers everything in
+// a .ssh directory.
+func (p *FilePolicy) denied(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return "", false
+	}
+	elements := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range p.deny {
+		for i := range elements {
+			candidate := elements[i]
+			if strings.Contains(pattern, "/") {
+				candidate = strings.Join(elements[:i+1], "/")
+			}
+			if ok, _ := filepath.Match(pattern, candidate); ok {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// maxLinkDepth bounds how many dangling symlinks realPath follows
+const maxLinkDepth = 40
+
+// realPath returns the absolute form of path with symlinks resolved. Parts
+// that don't exist yet, such as a file about to be written, are kept as
+// they are below the deepest existing directory.
+func realPath(path string) string {
+	return resolvePath(path, 0)
+}
+
+func resolvePath(path string, depth int) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	var missing []string
+	current := abs
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		// A dangling symlink would be followed by a write, so resolve it by hand
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 && depth < maxLinkDepth {
+			if target, err := os.Readlink(current); err == nil {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(filepath.Dir(current), target)
+				}
+				return resolvePath(filepath.Join(append([]string{target}, missing...)...), depth+1)
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Orkflow/pkg/types"
+)
+
+func TestFileToolPolicy(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("x", 100)), 0644)
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("key"), 0644)
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(outside, "new"), filepath.Join(root, "dangling"))
+
+	var denials []string
+	file := &FileTool{
+		Policy: NewFilePolicy(types.FileAccessConfig{
+			Roots:       []string{root},
+			Mode:        types.FileAccessReadWrite,
+			MaxFileSize: 50,
+			Deny:        []string{".ssh", "*.pem"},
+		}),
+		OnDeny: func(input, reason string) { denials = append(denials, input) },
+	}
+
+	if out, err := file.Execute(context.Background(), "read:"+filepath.Join(root, "notes.txt")); err != nil || out != "hello" {
+		t.Errorf("expected to read inside the root, got %q, %v", out, err)
+	}
+	if _, err := file.Execute(context.Background(), "write:"+filepath.Join(root, "sub", "out.txt")+":ok"); err != nil {
+		t.Errorf("expected to write inside the root, got %v", err)
+	}
+
+	denied := []string{
+		"read:" + filepath.Join(outside, "secret"),
+		"read:" + filepath.Join(root, "..", filepath.Base(outside), "secret"),
+		"read:" + filepath.Join(root, "escape", "secret"),
+		"write:" + filepath.Join(root, "dangling") + ":data",
+		"write:" + filepath.Join(root, ".ssh", "authorized_keys") + ":key",
+		"read:" + filepath.Join(root, "certs", "server.pem"),
+		"read:" + filepath.Join(root, "big.txt"),
+		"write:" + filepath.Join(root, "big2.txt") + ":" + strings.Repeat("x", 51),
+	}
+	for _, input := range denied {
+		if _, err := file.Execute(context.Background(), input); !errors.Is(err, ErrFileAccessDenied) {
+			t.Errorf("%s: expected access to be denied, got %v", input, err)
+		}
+	}
+	if len(denials) != len(denied) {
+		t.Errorf("expected every denial to be reported, got %d of %d", len(denials), len(denied))
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new")); !os.IsNotExist(err) {
+		t.Error("the dangling symlink's target should not have been written")
+	}
+
+	readOnly := &FileTool{Policy: NewFilePolicy(types.FileAccessConfig{Roots: []string{root}})}
+	if _, err := readOnly.Execute(context.Background(), "write:"+filepath.Join(root, "x.txt")+":data"); !errors.Is(err, ErrFileAccessDenied) {
+		t.Errorf("expected writes to be denied by default, got %v", err)
+	}
+}
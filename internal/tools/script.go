@@ -1,28 +1,78 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+
+	"Orkflow/internal/memory"
+	"Orkflow/pkg/types"
 
 	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
 	"github.com/d5/tengo/v2/stdlib"
 )
 
-// ScriptTool executes Tengo scripts (Go-like syntax)
-type ScriptTool struct{}
+// Script tool defaults, used for limits a workflow leaves unset
+const (
+	DefaultScriptMaxAllocs = 5000000
+	DefaultScriptMaxSteps  = 1000000
+)
+
+// ErrScriptStepLimit is wrapped by the error for a script that runs more
+// loop iterations and function calls than its step limit
+var ErrScriptStepLimit = errors.New("script step limit exceeded")
+
+// stepHook is the function called at the top of every loop body and
+// function, counting the script's steps
+const stepHook = "__orka_step"
+
+// ScriptTool executes Tengo scripts (Go-like syntax). Scripts can import
+// the orka module to reach shared memory and the agent's other tools.
+type ScriptTool struct {
+	Memory    *memory.SharedMemory // Read and written by orka.get and orka.set when set
+	Tools     *Registry            // Tools orka.call may run when set
+	MaxAllocs int64                // Objects a run may allocate (default: DefaultScriptMaxAllocs)
+	MaxSteps  int64                // Loop iterations and function calls a run may make (default: DefaultScriptMaxSteps)
+}
 
 func init() {
 	Register(&ScriptTool{})
 }
 
+// NewScriptTool builds the script tool with a workflow's script limits
+func NewScriptTool(config types.ScriptConfig) *ScriptTool {
+	return &ScriptTool{MaxAllocs: config.MaxAllocs, MaxSteps: config.MaxSteps}
+}
+
 func (s *ScriptTool) Name() string {
 	return "script"
 }
 
 func (s *ScriptTool) Description() string {
-	return "Execute scripts using Tengo (Go-like syntax). Supports variables, loops, functions, math, and string operations. Set 'output' variable to return a value."
+	return "Execute scripts using Tengo (Go-like syntax). Supports variables, loops, functions, math, and string operations. Set 'output' variable to return a value. " +
+		"orka := import(\"orka\") gives orka.get(key), orka.set(key, value) and orka.keys() for shared memory, " +
+		"and orka.call(tool, input) to run another of your tools (input is a string or a map of arguments; failures return an error value)."
+}
+
+func (s *ScriptTool) InputSchema() map[string]interface{} {
+	return objectSchema(map[string]string{
+		"code": "Tengo source to run. Assign the result to 'output'.",
+	}, "code")
 }
 
-func (s *ScriptTool) Execute(input string) (string, error) {
+func (s *ScriptTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	return s.Execute(ctx, stringArg(args, "code"))
+}
+
+func (s *ScriptTool) Execute(ctx context.Context, input string) (string, error) {
+	if strings.Contains(input, stepHook) {
+		return "", fmt.Errorf("script error: %s is reserved", stepHook)
+	}
+
 	// Wrap script to capture output variable
 	wrappedScript := fmt.Sprintf(`
 output := ""
@@ -32,21 +82,44 @@ __run := func() {
 __run()
 `, input)
 
-	// Create script with standard library
-	script := tengo.NewScript([]byte(wrappedScript))
+	// Count steps so runaway loops are stopped
+	source, err := countSteps([]byte(wrappedScript))
+	if err != nil {
+		return "", fmt.Errorf("script error: %w", err)
+	}
+	script := tengo.NewScript(source)
 
 	// Add standard library modules
-	script.SetImports(stdlib.GetModuleMap(
+	modules := stdlib.GetModuleMap(
 		"fmt",
 		"math",
 		"text",
 		"times",
 		"rand",
 		"json",
-	))
+	)
+	modules.AddBuiltinModule("orka", s.module(ctx))
+	script.SetImports(modules)
+
+	maxAllocs, maxSteps := s.MaxAllocs, s.MaxSteps
+	if maxAllocs <= 0 {
+		maxAllocs = DefaultScriptMaxAllocs
+	}
+	if maxSteps <= 0 {
+		maxSteps = DefaultScriptMaxSteps
+	}
+	script.SetMaxAllocs(maxAllocs)
+	steps := int64(0)
+	script.Add(stepHook, &tengo.UserFunction{Name: stepHook, Value: func(args ...tengo.Object) (tengo.Object, error) {
+		steps++
+		if steps > maxSteps {
+			return nil, fmt.Errorf("%w (%d loop iterations and function calls)", ErrScriptStepLimit, maxSteps)
+		}
+		return tengo.UndefinedValue, nil
+	}})
 
 	// Run the script
-	compiled, err := script.Run()
+	compiled, err := script.RunContext(ctx)
 	if err != nil {
 		return "", fmt.Errorf("script error: %w", err)
 	}
@@ -60,6 +133,211 @@ __run()
 	return output.String(), nil
 }
 
+// module returns the orka module for one run of a script
+func (s *ScriptTool) module(ctx context.Context) map[string]tengo.Object {
+	return map[string]tengo.Object{
+		"get": &tengo.UserFunction{Name: "get", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) != 1 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			key, err := stringObject("key", args[0])
+			if err != nil {
+				return nil, err
+			}
+			if s.Memory == nil {
+				return nil, errNoScriptMemory
+			}
+			value, ok := s.Memory.Get(key)
+			if !ok {
+				return tengo.UndefinedValue, nil
+			}
+			obj, err := tengo.FromInterface(value)
+			if err != nil {
+				return &tengo.String{Value: fmt.Sprintf("%v", value)}, nil
+			}
+			return obj, nil
+		}},
+		"set": &tengo.UserFunction{Name: "set", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) != 2 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			key, err := stringObject("key", args[0])
+			if err != nil {
+				return nil, err
+			}
+			if s.Memory == nil {
+				return nil, errNoScriptMemory
+			}
+			s.Memory.Set(key, tengo.ToInterface(args[1]))
+			return tengo.UndefinedValue, nil
+		}},
+		"keys": &tengo.UserFunction{Name: "keys", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) != 0 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			if s.Memory == nil {
+				return nil, errNoScriptMemory
+			}
+			keys := s.Memory.Keys()
+			sort.Strings(keys)
+			result := &tengo.Array{}
+			for _, key := range keys {
+				result.Value = append(result.Value, &tengo.String{Value: key})
+			}
+			return result, nil
+		}},
+		"call": &tengo.UserFunction{Name: "call", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			if len(args) != 2 {
+				return nil, tengo.ErrWrongNumArguments
+			}
+			name, err := stringObject("tool", args[0])
+			if err != nil {
+				return nil, err
+			}
+			call := ToolCall{Name: name}
+			if input, ok := tengo.ToInterface(args[1]).(map[string]interface{}); ok {
+				data, _ := json.Marshal(input)
+				call.Input, call.Args = string(data), input
+			} else if call.Input, ok = tengo.ToString(args[1]); !ok {
+				return nil, tengo.ErrInvalidArgumentType{Name: "input", Expected: "string or map", Found: args[1].TypeName()}
+			}
+
+			registry := s.Tools
+			if registry == nil {
+				registry = NewRegistry()
+			}
+			result := registry.ExecuteToolCalls(ctx, []ToolCall{call})[0]
+			if result.Error != nil {
+				return &tengo.Error{Value: &tengo.String{Value: result.Error.Error()}}, nil
+			}
+			return &tengo.String{Value: result.Output}, nil
+		}},
+	}
+}
+
+var errNoScriptMemory = errors.New("shared memory is not available to this script")
+
+// stringObject reads a string argument of an orka function
+func stringObject(name string, obj tengo.Object) (string, error) {
+	if s, ok := obj.(*tengo.String); ok {
+		return s.Value, nil
+	}
+	return "", tengo.ErrInvalidArgumentType{Name: name, Expected: "string", Found: obj.TypeName()}
+}
+
+// countSteps inserts a call to the step hook at the top of every loop body
+// and function in a script. Tengo can cap allocations but not instructions,
+// and only loops and calls can keep a script running.
+func countSteps(src []byte) ([]byte, error) {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile("script", -1, len(src))
+	parsed, err := parser.NewParser(file, src, nil).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var offsets []int
+	mark := func(body *parser.BlockStmt) {
+		if body != nil {
+			offsets = append(offsets, file.Offset(body.LBrace)+1)
+		}
+	}
+	var walkStmt func(parser.Stmt)
+	var walkExpr func(parser.Expr)
+	walkExprs := func(exprs []parser.Expr) {
+		for _, e := range exprs {
+			walkExpr(e)
+		}
+	}
+	walkStmt = func(s parser.Stmt) {
+		switch s := s.(type) {
+		case *parser.AssignStmt:
+			walkExprs(s.LHS)
+			walkExprs(s.RHS)
+		case *parser.BlockStmt:
+			for _, stmt := range s.Stmts {
+				walkStmt(stmt)
+			}
+		case *parser.ExportStmt:
+			walkExpr(s.Result)
+		case *parser.ExprStmt:
+			walkExpr(s.Expr)
+		case *parser.ForInStmt:
+			walkExpr(s.Iterable)
+			mark(s.Body)
+			walkStmt(s.Body)
+		case *parser.ForStmt:
+			walkStmt(s.Init)
+			walkExpr(s.Cond)
+			walkStmt(s.Post)
+			mark(s.Body)
+			walkStmt(s.Body)
+		case *parser.IfStmt:
+			walkStmt(s.Init)
+			walkExpr(s.Cond)
+			walkStmt(s.Body)
+			walkStmt(s.Else)
+		case *parser.IncDecStmt:
+			walkExpr(s.Expr)
+		case *parser.ReturnStmt:
+			walkExpr(s.Result)
+		}
+	}
+	walkExpr = func(e parser.Expr) {
+		switch e := e.(type) {
+		case *parser.ArrayLit:
+			walkExprs(e.Elements)
+		case *parser.BinaryExpr:
+			walkExpr(e.LHS)
+			walkExpr(e.RHS)
+		case *parser.CallExpr:
+			walkExpr(e.Func)
+			walkExprs(e.Args)
+		case *parser.CondExpr:
+			walkExpr(e.Cond)
+			walkExpr(e.True)
+			walkExpr(e.False)
+		case *parser.ErrorExpr:
+			walkExpr(e.Expr)
+		case *parser.FuncLit:
+			mark(e.Body)
+			walkStmt(e.Body)
+		case *parser.ImmutableExpr:
+			walkExpr(e.Expr)
+		case *parser.IndexExpr:
+			walkExpr(e.Expr)
+			walkExpr(e.Index)
+		case *parser.MapLit:
+			for _, element := range e.Elements {
+				walkExpr(element.Value)
+			}
+		case *parser.ParenExpr:
+			walkExpr(e.Expr)
+		case *parser.SelectorExpr:
+			walkExpr(e.Expr)
+			walkExpr(e.Sel)
+		case *parser.SliceExpr:
+			walkExpr(e.Expr)
+			walkExpr(e.Low)
+			walkExpr(e.High)
+		case *parser.UnaryExpr:
+			walkExpr(e.Expr)
+		}
+	}
+	for _, stmt := range parsed.Stmts {
+		walkStmt(stmt)
+	}
+
+	// Insert from the end so earlier offsets stay valid. Nothing adds a
+	// line, so error positions still match the script.
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	result := append([]byte{}, src...)
+	for _, offset := range offsets {
+		result = append(result[:offset], append([]byte(stepHook+"();"), result[offset:]...)...)
+	}
+	return result, nil
+}
+
 // Example usage in prompts:
 // ```tool:script
 // a := 10
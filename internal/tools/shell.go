@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+// Shell tool defaults, used for settings a workflow leaves unset
+const (
+	DefaultShellTimeout   = 60 * time.Second
+	DefaultShellMaxOutput = 16384
+)
+
+// DefaultShellEnv lists the environment variables commands see when the
+// workflow doesn't say
+var DefaultShellEnv = []string{"PATH", "HOME"}
+
+// ErrCommandNotAllowed is wrapped by every error for a command outside the
+// shell allow-list
+var ErrCommandNotAllowed = errors.New("command not allowed")
+
+// ShellTool runs allow-listed commands in a working directory. It is only
+// available to workflows with a shell section, so it is registered with a
+// workflow's registry rather than at init.
+type ShellTool struct {
+	allow     [][]string
+	dir       string
+	env       []string
+	timeout   time.Duration
+	maxOutput int
+
+	Policy *FilePolicy                // Confines the working directory and path arguments when set
+	OnDeny func(input, reason string) // Told about each refused command when set
+}
+
+// NewShellTool builds the shell tool a workflow's shell section describes.
+// Without a configured directory, commands run in the first root of the
+// file policy, or the current directory when there is none.
+func NewShellTool(config types.ShellConfig, policy *FilePolicy) *ShellTool {
+	tool := &ShellTool{
+		dir:       config.Dir,
+		env:       config.Env,
+		timeout:   config.Timeout,
+		maxOutput: config.MaxOutput,
+		Policy:    policy,
+	}
+	for _, entry := range config.Allow {
+		if fields := strings.Fields(entry); len(fields) > 0 {
+			tool.allow = append(tool.allow, fields)
+		}
+	}
+	if tool.dir == "" && policy != nil && len(policy.roots) > 0 {
+		tool.dir = policy.roots[0]
+	}
+	if tool.dir == "" {
+		tool.dir = "."
+	}
+	tool.dir = realPath(tool.dir)
+	if len(tool.env) == 0 {
+		tool.env = DefaultShellEnv
+	}
+	if tool.timeout <= 0 {
+		tool.timeout = DefaultShellTimeout
+	}
+	if tool.maxOutput <= 0 {
+		tool.maxOutput = DefaultShellMaxOutput
+	}
+	return tool
+}
+
+func (s *ShellTool) Name() string {
+	return "shell"
+}
+
+func (s *ShellTool) Description() string {
+	allowed := make([]string, 0, len(s.allow))
+	for _, fields := range s.allow {
+		allowed = append(allowed, strings.Join(fields, " "))
+	}
+	return fmt.Sprintf("Run a command, e.g. 'go test ./...'. Only these commands are allowed: %s. "+
+		"There is no shell: pipes, redirects and globs don't work. Returns the output and exit code.", strings.Join(allowed, ", "))
+}
+
+func (s *ShellTool) InputSchema() map[string]interface{} {
+	return objectSchema(map[string]string{
+		"command": "Command line to run, e.g. git diff --stat",
+		"dir":     "Subdirectory to run in, relative to the working directory (optional)",
+	}, "command")
+}
+
+func (s *ShellTool) ExecuteArgs(ctx context.Context, args map[string]interface{}) (string, error) {
+	return s.run(ctx, stringArg(args, "command"), stringArg(args, "dir"))
+}
+
+func (s *ShellTool) Execute(ctx context.Context, input string) (string, error) {
+	return s.run(ctx, strings.TrimSpace(input), "")
+}
+
+// run checks a command line against the allow-list and sandbox, runs it and
+// reports its output and exit code
+func (s *ShellTool) run(ctx context.Context, command, subdir string) (string, error) {
+	argv, err := splitCommand(command)
+	if err != nil {
+		return "", err
+	}
+	if len(argv) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	dir, err := s.check(argv, subdir)
+	if err != nil {
+		if s.OnDeny != nil {
+			s.OnDeny(command, err.Error())
+		}
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = s.environ()
+	cmd.WaitDelay = time.Second // Don't wait on pipes held open by the command's children
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command timed out after %v: %s", s.timeout, command)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", ctxErr
+	}
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("failed to run %s: %w", argv[0], err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return fmt.Sprintf("%s\n[exit code %d]", truncateOutput(strings.TrimRight(output.String(), "\n"), s.maxOutput), exitCode), nil
+}
+
+// check enforces the allow-list and sandbox, returning the directory to run in
+func (s *ShellTool) check(argv []string, subdir string) (string, error) {
+	if !s.allowed(argv) {
+		return "", fmt.Errorf("%w: %s", ErrCommandNotAllowed, strings.Join(argv, " "))
+	}
+
+	dir := s.dir
+	if subdir != "" {
+		if filepath.IsAbs(subdir) {
+			dir = subdir
+		} else {
+			dir = filepath.Join(s.dir, subdir)
+		}
+	}
+	if s.Policy == nil {
+		return dir, nil
+	}
+
+	resolved, err := s.Policy.check("", dir, 0)
+	if err != nil {
+		return "", err
+	}
+	// Arguments that look like paths must stay inside the roots too
+	for _, arg := range argv[1:] {
+		if strings.HasPrefix(arg, "-") {
+			if i := strings.Index(arg, "="); i >= 0 {
+				arg = arg[i+1:]
+			} else {
+				continue
+			}
+		}
+		if !strings.Contains(arg, "/") && arg != ".." {
+			continue
+		}
+		path := arg
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(resolved, path)
+		}
+		if _, err := s.Policy.check("", path, 0); err != nil {
+			return "", err
+		}
+	}
+	return resolved, nil
+}
+
+// allowed reports whether some allow-list entry matches the start of argv.
+// Commands are matched by name, so "go" doesn't allow "/tmp/go".
+func (s *ShellTool) allowed(argv []string) bool {
+	for _, entry := range s.allow {
+		if len(entry) > len(argv) {
+			continue
+		}
+		match := true
+		for i, field := range entry {
+			if argv[i] != field {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// environ returns the variables passed through to commands
+func (s *ShellTool) environ() []string {
+	var env []string
+	for _, name := range s.env {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// truncateOutput keeps the start and end of long output, where compilers and
+// test runners put what matters most
+func truncateOutput(output string, max int) string {
+	if len(output) <= max {
+		return output
+	}
+	half := max / 2
+	return fmt.Sprintf("%s\n... [%d bytes truncated] ...\n%s", output[:half], len(output)-2*half, output[len(output)-half:])
+}
+
+// splitCommand splits a command line into arguments, honouring single and
+// double quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command: %s", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
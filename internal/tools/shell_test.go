@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Orkflow/pkg/types"
+)
+
+func TestShellTool(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0644)
+	t.Setenv("ORKA_SHELL_SECRET", "s3cret")
+
+	var denials []string
+	shell := NewShellTool(types.ShellConfig{
+		Allow:     []string{"cat", "env", "ls", "false", "git status"},
+		MaxOutput: 64,
+	}, NewFilePolicy(types.FileAccessConfig{Roots: []string{root}}))
+	shell.OnDeny = func(input, reason string) { denials = append(denials, input) }
+
+	out, err := shell.Execute(context.Background(), "cat notes.txt")
+	if err != nil || out != "hello\n[exit code 0]" {
+		t.Errorf("expected to run in the first root, got %q, %v", out, err)
+	}
+	if out, _ := shell.Execute(context.Background(), "false"); !strings.HasSuffix(out, "[exit code 1]") {
+		t.Errorf("expected the exit code to be reported, got %q", out)
+	}
+	if out, _ := shell.Execute(context.Background(), "env"); strings.Contains(out, "s3cret") {
+		t.Errorf("expected unlisted variables to be filtered out, got %q", out)
+	}
+
+	denied := []string{
+		"rm -rf notes.txt",
+		"git push",
+		"/bin/cat notes.txt",
+		"cat /etc/passwd",
+		"cat ../" + filepath.Base(root) + "-other/x",
+		"ls --directory=/etc",
+	}
+	for _, command := range denied {
+		if _, err := shell.Execute(context.Background(), command); err == nil {
+			t.Errorf("%s: expected the command to be refused", command)
+		}
+	}
+	if len(denials) != len(denied) {
+		t.Errorf("expected every refusal to be reported, got %d of %d", len(denials), len(denied))
+	}
+	if _, err := shell.Execute(context.Background(), "rm x"); !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("expected ErrCommandNotAllowed, got %v", err)
+	}
+	if _, err := shell.ExecuteArgs(context.Background(), map[string]interface{}{"command": "ls", "dir": "/"}); !errors.Is(err, ErrFileAccessDenied) {
+		t.Errorf("expected a directory outside the roots to be refused, got %v", err)
+	}
+}
+
+func TestShellToolLimits(t *testing.T) {
+	shell := NewShellTool(types.ShellConfig{Allow: []string{"sleep", "seq"}, Timeout: 100 * time.Millisecond, MaxOutput: 40}, nil)
+
+	if _, err := shell.Execute(context.Background(), "sleep 5"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	out, err := shell.Execute(context.Background(), "seq 1000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out, "1\n2\n") || !strings.Contains(out, "bytes truncated") || !strings.Contains(out, "1000\n[exit code 0]") {
+		t.Errorf("expected the start and end of the output, got %q", out)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	args, err := splitCommand(`git commit -m "fix: handle 'quotes'" --author='A B' a\ b`)
+	if err != nil {
+		t.Fatalf("splitCommand() error: %v", err)
+	}
+	want := []string{"git", "commit", "-m", "fix: handle 'quotes'", "--author=A B", "a b"}
+	if strings.Join(args, "|") != strings.Join(want, "|") {
+		t.Errorf("splitCommand() = %q, want %q", args, want)
+	}
+	if _, err := splitCommand(`echo "open`); err == nil {
+		t.Error("expected an unterminated quote error")
+	}
+}
@@ -1,7 +1,14 @@
 package tools
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"Orkflow/internal/memory"
+
+	"github.com/d5/tengo/v2"
 )
 
 func TestCalcTool(t *testing.T) {
@@ -20,7 +27,7 @@ func TestCalcTool(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result, err := calc.Execute(tt.input)
+		result, err := calc.Execute(context.Background(), tt.input)
 		if err != nil {
 			t.Errorf("calc.Execute(%q) error: %v", tt.input, err)
 			continue
@@ -35,7 +42,7 @@ func TestScriptTool(t *testing.T) {
 	script := &ScriptTool{}
 
 	// Simple addition
-	result, err := script.Execute(`
+	result, err := script.Execute(context.Background(), `
 		a := 10
 		b := 20
 		output = a + b
@@ -48,7 +55,7 @@ func TestScriptTool(t *testing.T) {
 	}
 
 	// Loop
-	result, err = script.Execute(`
+	result, err = script.Execute(context.Background(), `
 		sum := 0
 		for i := 1; i <= 5; i++ {
 			sum = sum + i
@@ -67,7 +74,7 @@ func TestFileTool(t *testing.T) {
 	file := &FileTool{}
 
 	// Test exists
-	result, err := file.Execute("exists:/tmp")
+	result, err := file.Execute(context.Background(), "exists:/tmp")
 	if err != nil {
 		t.Errorf("file.exists error: %v", err)
 	}
@@ -76,7 +83,7 @@ func TestFileTool(t *testing.T) {
 	}
 
 	// Test list
-	result, err = file.Execute("list:/tmp")
+	result, err = file.Execute(context.Background(), "list:/tmp")
 	if err != nil {
 		t.Errorf("file.list error: %v", err)
 	}
@@ -105,3 +112,138 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestScriptToolCancelled(t *testing.T) {
+	script := &ScriptTool{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := script.Execute(ctx, `for { }`)
+	if err == nil {
+		t.Fatal("expected an endless script to stop when the context is done")
+	}
+}
+
+func TestScriptToolOrkaModule(t *testing.T) {
+	shared := memory.NewSharedMemory("test")
+	shared.Set("researcher", map[string]interface{}{"topic": "go", "score": 3})
+	script := &ScriptTool{Memory: shared, Tools: NewRegistry(&CalcTool{})}
+
+	result, err := script.Execute(context.Background(), `
+		orka := import("orka")
+		found := orka.get("researcher")
+		doubled := orka.call("calc", string(found.score) + " * 2")
+		orka.set("summary", {topic: found.topic, doubled: doubled})
+		missing := orka.call("file", "read:/etc/hostname")
+		output = is_error(missing) ? orka.keys() : "file was callable"
+	`)
+	if err != nil {
+		t.Fatalf("script error: %v", err)
+	}
+	if result != `["researcher", "summary"]` {
+		t.Errorf("expected the keys after a refused call, got %q", result)
+	}
+	summary, _ := shared.Get("summary")
+	if m, ok := summary.(map[string]interface{}); !ok || m["topic"] != "go" || m["doubled"] != "6" {
+		t.Errorf("expected the script to write shared memory, got %#v", summary)
+	}
+
+	if _, err := (&ScriptTool{}).Execute(context.Background(), `orka := import("orka"); orka.set("k", 1)`); err == nil {
+		t.Error("expected orka.set without shared memory to fail")
+	}
+}
+
+func TestScriptToolLimits(t *testing.T) {
+	script := &ScriptTool{MaxSteps: 1000}
+
+	if _, err := script.Execute(context.Background(), `for { }`); !errors.Is(err, ErrScriptStepLimit) {
+		t.Errorf("expected an endless loop to hit the step limit, got %v", err)
+	}
+	if _, err := script.Execute(context.Background(), `f := func(n) { return f(n+1) }; f(0)`); !errors.Is(err, ErrScriptStepLimit) {
+		t.Errorf("expected runaway recursion to hit the step limit, got %v", err)
+	}
+	if out, err := script.Execute(context.Background(), `s := 0; for i := 0; i < 500; i++ { s += i }; output = s`); err != nil || out != "124750" {
+		t.Errorf("expected a bounded loop to run, got %q, %v", out, err)
+	}
+
+	script = &ScriptTool{MaxAllocs: 100}
+	if _, err := script.Execute(context.Background(), `a := []; for i := 0; i < 1000; i++ { a = append(a, [i]) }`); !errors.Is(err, tengo.ErrObjectAllocLimit) {
+		t.Errorf("expected the allocation limit to stop the script, got %v", err)
+	}
+	if _, err := script.Execute(context.Background(), stepHook+"()"); err == nil {
+		t.Error("expected the step hook name to be reserved")
+	}
+}
+
+func TestExecuteToolCallsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := NewRegistry(&CalcTool{}).ExecuteToolCalls(ctx, []ToolCall{{Name: "calc", Input: "1 + 1"}})
+	if len(results) != 1 || results[0].Error != context.Canceled {
+		t.Errorf("expected a cancelled result, got %+v", results)
+	}
+}
+
+func TestExecuteToolCallsStructuredArgs(t *testing.T) {
+	results := NewRegistry(&CalcTool{}, &FileTool{}).ExecuteToolCalls(context.Background(), []ToolCall{
+		{Name: "calc", Args: map[string]interface{}{"expression": "6 * 7"}},
+		{Name: "file", Args: map[string]interface{}{"operation": "exists", "path": "/tmp"}},
+	})
+	if results[0].Error != nil || results[0].Output != "42" {
+		t.Errorf("expected calc to read the expression argument, got %+v", results[0])
+	}
+	if results[1].Error != nil || results[1].Output != "true (directory)" {
+		t.Errorf("expected file to read operation and path, got %+v", results[1])
+	}
+}
+
+func TestParseToolCallsDottedNames(t *testing.T) {
+	calls := ParseToolCalls("```tool:filesystem.list_directory\n{\"path\": \"/tmp\"}\n```")
+	if len(calls) != 1 || calls[0].Name != "filesystem.list_directory" {
+		t.Errorf("expected an MCP tool call, got %+v", calls)
+	}
+}
+
+func TestInputSchemas(t *testing.T) {
+	for _, tool := range GetAll() {
+		schema := tool.InputSchema()
+		if schema["type"] != "object" {
+			t.Errorf("tool %s: expected an object schema, got %v", tool.Name(), schema)
+		}
+	}
+}
+
+func TestRegistriesAreScoped(t *testing.T) {
+	first, second := NewWorkflowRegistry(), NewWorkflowRegistry()
+	first.Register(namedTool{name: "server.lookup"})
+
+	if _, ok := second.Get("server.lookup"); ok {
+		t.Error("a tool registered in one workflow's registry leaked into another")
+	}
+	if _, ok := Get("server.lookup"); ok {
+		t.Error("a workflow's tool leaked into the built-in registry")
+	}
+	if _, ok := second.Get("file"); !ok {
+		t.Error("expected workflow registries to start with the built-in tools")
+	}
+
+	results := NewRegistry(&CalcTool{}).ExecuteToolCalls(context.Background(), []ToolCall{
+		{Name: "calc", Input: "1 + 1"},
+		{Name: "file", Input: "exists:/tmp"},
+	})
+	if results[0].Error != nil || results[1].Error == nil {
+		t.Errorf("expected only the registry's tools to run, got %+v", results)
+	}
+}
+
+// namedTool is a do-nothing tool with a given name
+type namedTool struct{ name string }
+
+func (n namedTool) Name() string                        { return n.name }
+func (n namedTool) Description() string                 { return "" }
+func (n namedTool) InputSchema() map[string]interface{} { return nil }
+func (n namedTool) Execute(ctx context.Context, input string) (string, error) {
+	return "", nil
+}
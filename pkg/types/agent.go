@@ -1,5 +1,11 @@
 package types
 
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 type Agent struct {
 	ID          string   `yaml:"id"`
 	Model       string   `yaml:"model"`
@@ -9,10 +15,23 @@ type Agent struct {
 	Toolsets    []string `yaml:"toolsets,omitempty"`
 	Description string   `yaml:"description,omitempty"`
 	Instruction string   `yaml:"instruction,omitempty"`
+	Appended    []string `yaml:"-"` // Runtime text added by WithInstruction, after the instruction
 	SubAgents   []string `yaml:"sub_agents,omitempty"`
 	Outputs     []string `yaml:"outputs,omitempty"`  // Keys to publish to shared memory
 	Requires    []string `yaml:"requires,omitempty"` // Keys to wait for before running
 
+	Timeout           time.Duration `yaml:"timeout,omitempty"`             // Max time for one run, e.g. "90s" (default: none)
+	MaxToolIterations int           `yaml:"max_tool_iterations,omitempty"` // Max rounds of tool calls per run (default: 10)
+	Retry             *RetryConfig  `yaml:"retry,omitempty"`               // Overrides the model's retry policy
+	OutputSchema      *OutputSchema `yaml:"output_schema,omitempty"`       // JSON Schema the reply must match
+
+	// Generation parameters, overriding the model's
+	GenerationParams `yaml:",inline"`
+
+	// Supervisor fields
+	MaxDelegations int `yaml:"max_delegations,omitempty"` // Max sub-agent calls per run (default: 10)
+	MaxDepth       int `yaml:"max_depth,omitempty"`       // Max levels of nested supervisors, counting this one (default: 3)
+
 	// Collaborative workflow fields
 	ListensTo    []string `yaml:"listens_to,omitempty"`     // Agent IDs to receive messages from
 	MaxTurns     int      `yaml:"max_turns,omitempty"`      // Max conversation turns (default: 5)
@@ -23,11 +42,43 @@ type Agent struct {
 	ContextTopK      int  `yaml:"context_top_k,omitempty"`      // Number of relevant docs to retrieve (default: 5)
 }
 
+// OutputSchema is a JSON Schema for an agent's reply, written inline or as
+// the path of a JSON or YAML file relative to the workflow file. The parser
+// loads files into Schema.
+type OutputSchema struct {
+	Path   string
+	Schema map[string]interface{}
+}
+
+// UnmarshalYAML accepts either a file path or an inline schema
+func (o *OutputSchema) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&o.Path)
+	}
+	return value.Decode(&o.Schema)
+}
+
+// GetPrompt returns the agent's instruction, or its goal, followed by the
+// text added with WithInstruction
 func (a *Agent) GetPrompt() string {
+	prompt := a.Goal
 	if a.Instruction != "" {
-		return a.Instruction
+		prompt = a.Instruction
 	}
-	return a.Goal
+	for _, extra := range a.Appended {
+		prompt += "\n\n" + extra
+	}
+	return prompt
+}
+
+// WithInstruction returns a copy of the agent whose prompt has extra text
+// appended, leaving the original definition untouched. The text is kept
+// apart from the goal and instruction, so prompt templates are not
+// evaluated in it.
+func (a *Agent) WithInstruction(extra string) *Agent {
+	clone := *a
+	clone.Appended = append(append([]string{}, a.Appended...), extra)
+	return &clone
 }
 
 func (a *Agent) IsSupervisor() bool {
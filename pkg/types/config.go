@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // MCPServerConfig defines an MCP server configuration
 type MCPServerConfig struct {
 	Command string   `yaml:"command"`
@@ -12,5 +14,68 @@ type WorkflowConfig struct {
 	Workflow   *WorkflowSpec              `yaml:"workflow,omitempty"`
 	Models     map[string]Model           `yaml:"models,omitempty"`
 	MCPServers map[string]MCPServerConfig `yaml:"mcp_servers,omitempty"`
-	Memory     *MemoryConfig              `yaml:"memory,omitempty"` // Vector memory configuration
+	Memory     *MemoryConfig              `yaml:"memory,omitempty"`      // Vector memory configuration
+	Budget     *BudgetConfig              `yaml:"budget,omitempty"`      // Limits that abort the run when reached
+	FileAccess *FileAccessConfig          `yaml:"file_access,omitempty"` // Sandbox for the file and shell tools
+	Shell      *ShellConfig               `yaml:"shell,omitempty"`       // Enables the shell tool
+	HTTP       *HTTPConfig                `yaml:"http,omitempty"`        // Hosts and credentials for the http tool
+	Script     *ScriptConfig              `yaml:"script,omitempty"`      // Limits for the script tool
+}
+
+// BudgetConfig caps what a workflow run may spend. Limits are checked before
+// every LLM call; zero means unlimited.
+type BudgetConfig struct {
+	MaxTokens  int     `yaml:"max_tokens,omitempty"`   // Input plus output tokens
+	MaxCostUSD float64 `yaml:"max_cost_usd,omitempty"` // Estimated cost from the pricing table
+	MaxCalls   int     `yaml:"max_calls,omitempty"`    // LLM calls, including tool follow-ups and turns
+}
+
+// File access modes
+const (
+	FileAccessReadOnly  = "read-only"
+	FileAccessReadWrite = "read-write"
+)
+
+// FileAccessConfig confines the file tool. Paths are checked after
+// resolving symlinks, so a link inside a root can't lead outside it.
+type FileAccessConfig struct {
+	Roots       []string `yaml:"roots"`                   // Directories the tool may use; relative roots are resolved from the working directory
+	Mode        string   `yaml:"mode,omitempty"`          // "read-only" or "read-write" (default: read-only)
+	MaxFileSize int64    `yaml:"max_file_size,omitempty"` // Max bytes read or written per file (default: unlimited)
+	Deny        []string `yaml:"deny,omitempty"`          // Globs refused even inside a root, e.g. ".env" or "*.pem"
+}
+
+// ShellConfig enables the shell tool and limits what it may run. Commands
+// run without a shell, so pipes, globs and redirects are not available.
+type ShellConfig struct {
+	Allow     []string      `yaml:"allow"`                // Commands that may run, e.g. "go" or "git diff"; each must match the start of the command line
+	Dir       string        `yaml:"dir,omitempty"`        // Working directory (default: the first file_access root, or the current directory)
+	Env       []string      `yaml:"env,omitempty"`        // Environment variables passed through (default: PATH and HOME)
+	Timeout   time.Duration `yaml:"timeout,omitempty"`    // Max run time per command (default: 60s)
+	MaxOutput int           `yaml:"max_output,omitempty"` // Max bytes of output returned to the model (default: 16384)
+}
+
+// HTTPConfig lets the http tool reach a set of hosts. Without it the tool
+// refuses every request.
+type HTTPConfig struct {
+	AllowHosts  []string           `yaml:"allow_hosts"`            // Hosts the tool may call, e.g. "api.example.com", "*.example.com" or "localhost:8080"
+	Headers     []HTTPHeaderConfig `yaml:"headers,omitempty"`      // Headers added to requests, with values from the environment
+	MaxResponse int                `yaml:"max_response,omitempty"` // Max bytes of response body returned (default: 65536)
+	Timeout     time.Duration      `yaml:"timeout,omitempty"`      // Max time per request (default: 30s)
+}
+
+// HTTPHeaderConfig injects a header, typically credentials, so the value
+// never appears in the workflow file or the model's context
+type HTTPHeaderConfig struct {
+	Host   string `yaml:"host,omitempty"`   // Host pattern the header is sent to (default: every allowed host)
+	Name   string `yaml:"name"`             // Header name, e.g. Authorization
+	Env    string `yaml:"env"`              // Environment variable holding the value
+	Prefix string `yaml:"prefix,omitempty"` // Prepended to the value, e.g. "Bearer "
+}
+
+// ScriptConfig limits what one run of the script tool may do, so runaway
+// scripts are stopped
+type ScriptConfig struct {
+	MaxAllocs int64 `yaml:"max_allocs,omitempty"` // Objects a run may allocate (default: 5000000)
+	MaxSteps  int64 `yaml:"max_steps,omitempty"`  // Loop iterations and function calls a run may make (default: 1000000)
 }
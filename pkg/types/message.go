@@ -0,0 +1,33 @@
+package types
+
+// Chat message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// ChatMessage is one message of a conversation with a model
+type ChatMessage struct {
+	Role       string     `json:"role"`                   // system, user, assistant or tool
+	Content    string     `json:"content"`                // Text content
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Calls requested by an assistant message
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Call a tool message answers
+	Name       string     `json:"name,omitempty"`         // Tool name of a tool message
+	Usage      *Usage     `json:"usage,omitempty"`        // Tokens the provider reported for a reply
+}
+
+// ToolCall is a tool invocation requested through a provider's native API
+type ToolCall struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// Usage is the token count a provider reported for one call
+type Usage struct {
+	InputTokens       int `json:"input_tokens"`                  // All prompt tokens, cached or not
+	CachedInputTokens int `json:"cached_input_tokens,omitempty"` // Prompt tokens read from the provider's cache
+	OutputTokens      int `json:"output_tokens"`
+}
@@ -1,9 +1,131 @@
 package types
 
+import (
+	"sort"
+	"time"
+)
+
 type Model struct {
-	Provider  string `yaml:"provider"`
-	Model     string `yaml:"model"`
-	Endpoint  string `yaml:"endpoint,omitempty"`
-	MaxTokens int    `yaml:"max_tokens,omitempty"`
-	APIKey    string `yaml:"api_key,omitempty"`
+	Provider  string       `yaml:"provider"`
+	Model     string       `yaml:"model"`
+	Endpoint  string       `yaml:"endpoint,omitempty"`
+	APIKey    string       `yaml:"api_key,omitempty"`
+	Retry     *RetryConfig `yaml:"retry,omitempty"`     // Retry policy of calls to this model
+	Fallbacks []string     `yaml:"fallbacks,omitempty"` // Models to switch to, in order, when this one is unavailable
+
+	GenerationParams `yaml:",inline"`
+}
+
+// InheritAPIKeys returns a copy of models in which each entry without an API
+// key takes the key of a parent entry for the same provider and endpoint,
+// preferring the parent entry of the same name
+func InheritAPIKeys(models, parent map[string]Model) map[string]Model {
+	result := make(map[string]Model, len(models))
+	for name, model := range models {
+		if model.APIKey == "" {
+			model.APIKey = parentKey(name, model, parent)
+		}
+		result[name] = model
+	}
+	return result
+}
+
+func parentKey(name string, model Model, parent map[string]Model) string {
+	sameAccount := func(p Model) bool {
+		return p.APIKey != "" && p.Provider == model.Provider && p.Endpoint == model.Endpoint
+	}
+	if p, ok := parent[name]; ok && sameAccount(p) {
+		return p.APIKey
+	}
+	names := make([]string, 0, len(parent))
+	for n := range parent {
+		names = append(names, n)
+	}
+	sort.Strings(names) // The same key every run when several entries match
+	for _, n := range names {
+		if sameAccount(parent[n]) {
+			return parent[n].APIKey
+		}
+	}
+	return ""
+}
+
+// Response formats a model can be asked for
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json"
+)
+
+// GenerationParams tune how a model generates. They can be set on a model
+// and on an agent; fields set on the agent win. Unset fields use the
+// provider's defaults.
+type GenerationParams struct {
+	Temperature    *float64 `yaml:"temperature,omitempty"`     // Pointer, since 0 is a meaningful setting
+	TopP           *float64 `yaml:"top_p,omitempty"`           // Nucleus sampling cutoff
+	MaxTokens      int      `yaml:"max_tokens,omitempty"`      // Max tokens to generate per call
+	Stop           []string `yaml:"stop,omitempty"`            // Sequences that end generation
+	Seed           *int     `yaml:"seed,omitempty"`            // For reproducible sampling, where supported
+	ResponseFormat string   `yaml:"response_format,omitempty"` // "text" or "json"
+
+	// JSON Schema the reply must match, from the agent's output_schema.
+	// Providers with structured output are asked to follow it.
+	Schema map[string]interface{} `yaml:"-"`
+}
+
+// Merge returns p with the fields set in override replacing its own
+func (p GenerationParams) Merge(override GenerationParams) GenerationParams {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		p.MaxTokens = override.MaxTokens
+	}
+	if len(override.Stop) > 0 {
+		p.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.ResponseFormat != "" {
+		p.ResponseFormat = override.ResponseFormat
+	}
+	if override.Schema != nil {
+		p.Schema = override.Schema
+	}
+	return p
+}
+
+// ErrorKind classifies a failed model call
+type ErrorKind string
+
+const (
+	ErrorRateLimited ErrorKind = "rate_limited" // 429 and similar; worth retrying after a pause
+	ErrorTransient   ErrorKind = "transient"    // Server errors, timeouts and dropped connections
+	ErrorAuth        ErrorKind = "auth"         // Missing or invalid credentials
+	ErrorQuota       ErrorKind = "quota"        // Billing or quota exhausted; retrying won't help
+	ErrorBadRequest  ErrorKind = "bad_request"  // The request itself was rejected
+)
+
+// Valid reports whether k is one of the known error kinds
+func (k ErrorKind) Valid() bool {
+	switch k {
+	case ErrorRateLimited, ErrorTransient, ErrorAuth, ErrorQuota, ErrorBadRequest:
+		return true
+	}
+	return false
+}
+
+// RetryConfig controls how failed model calls are retried. It can be set on
+// a model and on an agent; fields set on the agent win. Unset fields use the
+// defaults.
+type RetryConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts,omitempty"`  // Attempts per call, including the first (default: 3)
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty"` // Wait before the first retry (default: 1s)
+	MaxDelay     time.Duration `yaml:"max_delay,omitempty"`     // Cap on the backoff (default: 30s)
+	Multiplier   float64       `yaml:"multiplier,omitempty"`    // Backoff growth per retry (default: 2)
+	Jitter       float64       `yaml:"jitter,omitempty"`        // Random spread of each wait, 0 to 1 (default: 0.2)
+	RetryOn      []ErrorKind   `yaml:"retry_on,omitempty"`      // Error kinds to retry (default: rate_limited, transient)
 }
@@ -1,5 +1,11 @@
 package types
 
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
 // MemoryConfig configures the memory backend for the workflow
 type MemoryConfig struct {
 	Type        string `yaml:"type"`         // "simple" (default) or "vector"
@@ -8,17 +14,126 @@ type MemoryConfig struct {
 }
 
 type WorkflowSpec struct {
-	Type     string   `yaml:"type"`              // "sequential", "parallel", or "collaborative"
-	Steps    []Step   `yaml:"steps,omitempty"`
-	Branches []string `yaml:"branches,omitempty"`
-	Then     *Step    `yaml:"then,omitempty"`
+	Type     string        `yaml:"type"` // "sequential", "parallel", "collaborative", or "dag"
+	Steps    []Step        `yaml:"steps,omitempty"`
+	Branches []string      `yaml:"branches,omitempty"`
+	Then     *Step         `yaml:"then,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"` // Max time for the whole workflow, e.g. "10m" (default: none)
 
 	// Collaborative workflow fields
 	Collaborators []string `yaml:"collaborators,omitempty"` // Agents that can communicate
 	MaxTurns      int      `yaml:"max_turns,omitempty"`     // Global max turns (default: 10)
+	Termination   string   `yaml:"termination,omitempty"`   // "all" (default), "quorum", or "moderator"
+	Quorum        int      `yaml:"quorum,omitempty"`        // Agents that must send DONE (default: majority)
+	Moderator     string   `yaml:"moderator,omitempty"`     // Agent whose DONE ends the collaboration
+
+	// DAG workflow fields
+	MaxParallel int `yaml:"max_parallel,omitempty"` // Max nodes running at once (default: unlimited)
 }
 
 type Step struct {
-	Agent string `yaml:"agent"`
+	ID        string   `yaml:"id,omitempty"` // Node ID for dag workflows (defaults to agent)
+	Agent     string   `yaml:"agent,omitempty"`
+	DependsOn []string `yaml:"depends_on,omitempty"` // Node IDs that must finish first (dag only)
+
+	// Conditional execution
+	When   string      `yaml:"when,omitempty"`   // Expression that must be true for the step to run
+	Switch *SwitchSpec `yaml:"switch,omitempty"` // Route to a branch chosen by an agent
+
+	// Iteration
+	Loop    *LoopSpec    `yaml:"loop,omitempty"`    // Repeat a sub-sequence until a condition holds
+	ForEach *ForEachSpec `yaml:"foreach,omitempty"` // Run an agent once per item of a list
+
+	// Sub-workflow reference
+	Workflow    string            `yaml:"workflow,omitempty"` // Path to another workflow file, relative to this one
+	Inputs      map[string]string `yaml:"inputs,omitempty"`   // Child key <- parent key, copied in before the run
+	Outputs     map[string]string `yaml:"outputs,omitempty"`  // Parent key <- child key, copied out after the run
+	SubWorkflow *WorkflowConfig   `yaml:"-"`                  // Resolved by the parser
+}
+
+// SwitchSpec runs a router agent and then the steps of the case it picks.
+// The router replies with {"route": "<case>"}.
+type SwitchSpec struct {
+	Agent   string            `yaml:"agent"`             // Router agent
+	Cases   map[string][]Step `yaml:"cases"`             // Route name -> steps to run
+	Default []Step            `yaml:"default,omitempty"` // Steps when the route matches no case
+}
+
+// LoopSpec repeats Steps until the Until expression is true or
+// MaxIterations is reached. The expression can also read `iteration`.
+type LoopSpec struct {
+	Steps         []Step `yaml:"steps"`
+	Until         string `yaml:"until,omitempty"`
+	MaxIterations int    `yaml:"max_iterations,omitempty"` // Default: 5
+}
+
+// ForEachSpec fans an agent template out over the items of a shared memory
+// value (a JSON array or a newline-separated list) and collects the results
+// into a map keyed by item.
+type ForEachSpec struct {
+	Items       string `yaml:"items"`                  // Shared memory key holding the list
+	Agent       string `yaml:"agent"`                  // Agent template run once per item
+	MaxParallel int    `yaml:"max_parallel,omitempty"` // Max items in flight (default: unlimited)
+	Output      string `yaml:"output,omitempty"`       // Key for the collected results (default: <agent>_results)
+	Then        *Step  `yaml:"then,omitempty"`         // Step that consumes the collected results
+}
+
+// ResultsKey returns the shared memory key the collected results are stored under
+func (f *ForEachSpec) ResultsKey() string {
+	if f.Output != "" {
+		return f.Output
+	}
+	return f.Agent + "_results"
+}
+
+// NodeID returns the name other dag steps use to reference this step
+func (s *Step) NodeID() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	if s.Switch != nil {
+		return s.Switch.Agent
+	}
+	if s.Loop != nil {
+		return "loop"
+	}
+	if s.ForEach != nil {
+		return s.ForEach.Agent
+	}
+	if s.Workflow != "" {
+		return strings.TrimSuffix(filepath.Base(s.Workflow), filepath.Ext(s.Workflow))
+	}
+	return s.Agent
 }
 
+// SubWorkflows returns the workflows the spec's steps reference. Their own
+// sub-workflows are not included.
+func (w *WorkflowSpec) SubWorkflows() []*WorkflowConfig {
+	var configs []*WorkflowConfig
+	var visit func(steps []Step)
+	visitStep := func(step *Step) {
+		switch {
+		case step.SubWorkflow != nil:
+			configs = append(configs, step.SubWorkflow)
+		case step.Switch != nil:
+			for _, steps := range step.Switch.Cases {
+				visit(steps)
+			}
+			visit(step.Switch.Default)
+		case step.Loop != nil:
+			visit(step.Loop.Steps)
+		case step.ForEach != nil && step.ForEach.Then != nil:
+			visit([]Step{*step.ForEach.Then})
+		}
+	}
+	visit = func(steps []Step) {
+		for i := range steps {
+			visitStep(&steps[i])
+		}
+	}
+	visit(w.Steps)
+	if w.Then != nil {
+		visitStep(w.Then)
+	}
+	return configs
+}